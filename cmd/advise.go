@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mattb2401/pgterm/internal/pgterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	adviseSchema string
+
+	adviseCmd = &cobra.Command{
+		Use:   "advise",
+		Short: "Suggests maintenance for the database",
+	}
+
+	adviseIndexesCmd = &cobra.Command{
+		Use:   "indexes -u username -d database",
+		Short: "Reports unused, duplicate, invalid and missing foreign key indexes",
+		Long: `indexes command flags never-scanned, duplicate, prefix-redundant and invalid indexes
+    and foreign keys without a supporting index. Every finding comes with the DDL to fix it,
+    which is printed but never executed. --schema limits the report to one schema.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			db, err := openConnection(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				return
+			}
+			defer db.Close()
+			executor := pgterm.Executor{
+				DB: db,
+			}
+			resp, err := executor.IndexAdvice(adviseSchema)
+			if err != nil {
+				fmt.Println(err.Error())
+				return
+			}
+			fmt.Println(resp)
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(adviseCmd)
+	adviseCmd.AddCommand(adviseIndexesCmd)
+	addConnectionFlags(adviseIndexesCmd)
+	adviseIndexesCmd.Flags().StringVarP(&adviseSchema, "schema", "s", "", "Only report on this schema")
+}
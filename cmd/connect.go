@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"database/sql"
 	"fmt"
 	"os"
 
@@ -16,47 +17,45 @@ var (
 	requiresPassword bool
 	password         string
 	database         string
+	dryRun           bool
+	readOnly         bool
+	profileName      string
+	environment      string
+	breakGlass       bool
 
 	connectCmd = &cobra.Command{
 		Use:   "connect -h host(optional) -P port(optional) -u username -p <requires password>",
 		Short: "Connects to the Postgres database",
-		Long: `connect command connects to the database, -h takes a host address, if empty reverts back to localhost, 
-    -p is the port of the database instance on which it is running on. 
+		Long: `connect command connects to the database, -h takes a host address, if empty reverts back to localhost,
+    -p is the port of the database instance on which it is running on.
     -u is required as the username and -p if username password is set`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if len(username) <= 0 {
-				fmt.Println("Username is required, use the -u flag")
+			conn, err := connectionSettings(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
 				return
 			}
-			if len(database) <= 0 {
-				fmt.Println("Database is required, use the -d flag")
+			conn.Notices = true
+			db, err := dial(conn)
+			if err != nil {
+				fmt.Println(err.Error())
 				return
 			}
-			if cmd.Flags().Changed("requiresPassword") {
-				fmt.Print("Enter password: ")
-				bPassword, err := term.ReadPassword(int(os.Stdin.Fd()))
-				if err != nil {
-					fmt.Println("Error picking password from the stdin")
-					return
-				}
-				password = string(bPassword)
-			}
-			db, err := pgterm.InitiateConnection(&pgterm.Connection{
-				Host:     host,
-				Port:     port,
-				Username: username,
-				Password: password,
-				Database: database,
-				SSLConfig: pgterm.SSLConfig{
-					SSLMode: "disable",
-				},
-			})
+			audit, err := pgterm.OpenAuditLog(pgterm.AuditSettings(profileName),
+				fmt.Sprintf("%s@%s:%d/%s", username, host, port, database))
 			if err != nil {
-				fmt.Println("\nConnection Error: ", err.Error())
+				fmt.Printf("Audit Error: %s\n", err.Error())
+				db.Close()
 				return
 			}
 			prompt := pgterm.Prompt{
-				DB: db,
+				DB:         db,
+				DryRun:     dryRun,
+				ReadOnly:   readOnly,
+				Env:        environment,
+				Audit:      audit,
+				BreakGlass: breakGlass,
+				Connection: conn,
 			}
 			prompt.New()
 		},
@@ -65,10 +64,118 @@ var (
 
 func init() {
 	rootCmd.AddCommand(connectCmd)
-	connectCmd.Flags().BoolP("help", "", false, "")
-	connectCmd.Flags().StringVarP(&host, "host", "h", "localhost", "Server address")
-	connectCmd.Flags().IntVarP(&port, "port", "P", 5432, "Server Port")
-	connectCmd.Flags().StringVarP(&username, "username", "u", "", "")
-	connectCmd.Flags().StringVarP(&database, "database", "d", "", "")
-	connectCmd.Flags().BoolVarP(&requiresPassword, "requiresPassword", "p", true, "")
+	addConnectionFlags(connectCmd)
+	connectCmd.Flags().BoolVar(&readOnly, "read-only", false, "Open a read-only session and refuse write statements")
+	connectCmd.Flags().BoolVar(&breakGlass, "break-glass", false, "Allow changes to the protected objects of the config file")
+	connectCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Ask before committing any INSERT, UPDATE, DELETE or MERGE")
+}
+
+// addConnectionFlags registers the flags describing the database to connect to.
+// Commands that talk to the database share them so they are spelled the same everywhere.
+func addConnectionFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("help", "", false, "")
+	cmd.Flags().StringVarP(&host, "host", "h", "localhost", "Server address")
+	cmd.Flags().IntVarP(&port, "port", "P", 5432, "Server Port")
+	cmd.Flags().StringVarP(&username, "username", "u", "", "")
+	cmd.Flags().StringVarP(&database, "database", "d", "", "")
+	cmd.Flags().BoolVarP(&requiresPassword, "requiresPassword", "p", true, "")
+	cmd.Flags().StringVar(&profileName, "profile", "", "Connection profile from the config file")
+	cmd.Flags().StringVar(&environment, "env", "", "Environment label: dev, staging or prod")
+}
+
+// applyProfile fills in the connection settings of the named profile that were
+// not given as flags.
+func applyProfile(cmd *cobra.Command, name string) error {
+	profile, err := pgterm.LookupProfile(name)
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("host") && profile.Host != "" {
+		host = profile.Host
+	}
+	if !cmd.Flags().Changed("port") && profile.Port != 0 {
+		port = profile.Port
+	}
+	if !cmd.Flags().Changed("username") && profile.Username != "" {
+		username = profile.Username
+	}
+	if !cmd.Flags().Changed("database") && profile.Database != "" {
+		database = profile.Database
+	}
+	readOnly = readOnly || profile.ReadOnly
+	if !cmd.Flags().Changed("env") && profile.Environment != "" {
+		environment = profile.Environment
+	}
+	return nil
+}
+
+// openConnection connects to the database with the settings of the flags.
+func openConnection(cmd *cobra.Command) (*sql.DB, error) {
+	conn, err := connectionSettings(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return dial(conn)
+}
+
+// connectionSettings validates the connection flags, reads the password from the
+// terminal when -p is given and returns the settings to connect with.
+func connectionSettings(cmd *cobra.Command) (*pgterm.Connection, error) {
+	if err := pgterm.LoadConfig(); err != nil {
+		return nil, fmt.Errorf("Config Error: %s", err.Error())
+	}
+	if profileName != "" {
+		if err := applyProfile(cmd, profileName); err != nil {
+			return nil, err
+		}
+	}
+	if environment != "" {
+		env, err := pgterm.ParseEnvironment(environment)
+		if err != nil {
+			return nil, err
+		}
+		environment = env
+	} else {
+		environment = pgterm.DetectEnvironment(host)
+	}
+	if len(username) <= 0 {
+		return nil, fmt.Errorf("Username is required, use the -u flag")
+	}
+	if len(database) <= 0 {
+		return nil, fmt.Errorf("Database is required, use the -d flag")
+	}
+	if cmd.Flags().Changed("requiresPassword") {
+		fmt.Print("Enter password: ")
+		bPassword, err := term.ReadPassword(int(os.Stdin.Fd()))
+		if err != nil {
+			return nil, fmt.Errorf("Error picking password from the stdin")
+		}
+		password = string(bPassword)
+	}
+	options := map[string]string{
+		"application_name": pgterm.ApplicationName(environment),
+	}
+	if readOnly {
+		options["default_transaction_read_only"] = "on"
+	}
+	return &pgterm.Connection{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		Database: database,
+		SSLConfig: pgterm.SSLConfig{
+			SSLMode: "disable",
+		},
+		Options: options,
+	}, nil
+}
+
+// dial connects with the settings of connectionSettings.
+func dial(conn *pgterm.Connection) (*sql.DB, error) {
+	db, err := pgterm.InitiateConnection(conn)
+	if err != nil {
+		return nil, fmt.Errorf("\nConnection Error:  %s", err.Error())
+	}
+	return db, nil
 }
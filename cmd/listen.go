@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mattb2401/pgterm/internal/pgterm"
+	"github.com/spf13/cobra"
+)
+
+var listenCmd = &cobra.Command{
+	Use:   "listen <channel...> -u username -d database",
+	Short: "Prints NOTIFY messages as JSON lines",
+	Long: `listen command LISTENs on the channels and prints every notification to stdout as a
+    JSON line with time, channel, payload and pid until interrupted with Ctrl-C.
+    Channel names are case sensitive and used as given. Errors and reconnects go to stderr,
+    and the exit status is 1 when the connection or a LISTEN fails.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		conn, err := connectionSettings(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		if err := pgterm.TailNotifications(conn, args, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "listen: %s\n", err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listenCmd)
+	addConnectionFlags(listenCmd)
+}
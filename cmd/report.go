@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mattb2401/pgterm/internal/pgterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportLimit int
+	reportOrder string
+
+	reportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "Prints performance reports",
+	}
+
+	reportQueriesCmd = &cobra.Command{
+		Use:   "queries -u username -d database",
+		Short: "Reports the slowest queries from pg_stat_statements",
+		Long: `queries command lists the statements of the database from pg_stat_statements
+    with calls, total/mean/stddev time, rows and shared buffer hit ratio.
+    --order sorts by total, mean, calls or io and --limit caps the number of statements.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if reportLimit <= 0 {
+				return fmt.Errorf("--limit must be greater than 0, got %d", reportLimit)
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			db, err := openConnection(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				return
+			}
+			defer db.Close()
+			executor := pgterm.Executor{
+				DB: db,
+			}
+			resp, err := executor.SlowQueries(reportLimit, reportOrder, 0)
+			if err != nil {
+				fmt.Println(err.Error())
+				return
+			}
+			fmt.Println(resp)
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportQueriesCmd)
+	addConnectionFlags(reportQueriesCmd)
+	reportQueriesCmd.Flags().IntVarP(&reportLimit, "limit", "l", 20, "Number of statements to show")
+	reportQueriesCmd.Flags().StringVarP(&reportOrder, "order", "o", "total", "Sort by total, mean, calls or io")
+}
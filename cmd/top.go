@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattb2401/pgterm/internal/pgterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	topInterval float64
+
+	topCmd = &cobra.Command{
+		Use:   "top -u username -d database",
+		Short: "Shows live database activity",
+		Long: `top command opens a full-screen view of pg_stat_activity ordered by query duration,
+    with transaction rates, cache hit ratio and connection counts.
+    Press s to sort, u/d/t to filter by user, database or state, c to cancel a query,
+    k to terminate a backend and q to quit.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			db, err := openConnection(cmd)
+			if err != nil {
+				fmt.Println(err.Error())
+				return
+			}
+			defer db.Close()
+			top := pgterm.Top{
+				DB:       db,
+				Interval: time.Duration(topInterval * float64(time.Second)),
+				ReadOnly: readOnly,
+			}
+			if err := top.Run(); err != nil {
+				fmt.Println(err.Error())
+			}
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+	addConnectionFlags(topCmd)
+	topCmd.Flags().Float64VarP(&topInterval, "interval", "i", 2, "Refresh interval in seconds")
+	topCmd.Flags().BoolVar(&readOnly, "read-only", false, "Refuse cancelling and terminating backends")
+}
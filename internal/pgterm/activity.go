@@ -0,0 +1,128 @@
+package pgterm
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// processListQueryLength is the number of characters of a query shown by SHOW PROCESSLIST.
+// SHOW FULL PROCESSLIST shows the complete query text.
+const processListQueryLength = 100
+
+// processListSQL lists client backends from pg_stat_activity, oldest query first.
+const processListSQL = `
+    SELECT pid,
+           usename AS user,
+           datname AS database,
+           COALESCE(host(client_addr), 'local') AS client,
+           COALESCE(state, '') AS state,
+           COALESCE(wait_event_type || ':' || wait_event, '') AS wait_event,
+           COALESCE(date_trunc('second', now() - xact_start)::text, '') AS xact_age,
+           COALESCE(date_trunc('second', now() - query_start)::text, '') AS query_age,
+           CASE WHEN $1 > 0 AND length(query) > $1
+                THEN left(regexp_replace(query, '\s+', ' ', 'g'), $1) || '...'
+                ELSE regexp_replace(query, '\s+', ' ', 'g')
+           END AS query
+    FROM pg_stat_activity
+    WHERE backend_type = 'client backend'
+    ORDER BY query_start NULLS LAST;`
+
+// showProcessList renders the active client backends. When full is false the
+// query text is truncated to processListQueryLength characters.
+func (e *Executor) showProcessList(full bool) (string, error) {
+	length := processListQueryLength
+	if full {
+		length = 0
+	}
+	return e.renderQuery(processListSQL, length)
+}
+
+// kill parses KILL [QUERY | CONNECTION] <pid> and cancels the running query or
+// terminates the backend after confirmation.
+func (e *Executor) kill(args []string) (string, error) {
+	if session.GetReadOnly() {
+		return "", fmt.Errorf("Read-only session: KILL is not allowed")
+	}
+	if len(args) < 2 {
+		return "", fmt.Errorf("KILL needs a process id")
+	}
+	cancelOnly := false
+	pidArg := args[1]
+	switch strings.ToUpper(args[1]) {
+	case "QUERY":
+		cancelOnly = true
+		fallthrough
+	case "CONNECTION":
+		if len(args) < 3 {
+			return "", fmt.Errorf("KILL needs a process id")
+		}
+		pidArg = args[2]
+	}
+	pid, err := strconv.Atoi(pidArg)
+	if err != nil {
+		return "", fmt.Errorf("invalid process id: %s", pidArg)
+	}
+	return e.signalBackend(pid, cancelOnly)
+}
+
+// signalBackend asks for confirmation and then cancels the current query of the
+// backend (pg_cancel_backend) or terminates it (pg_terminate_backend).
+func (e *Executor) signalBackend(pid int, cancelOnly bool) (string, error) {
+	var user, database, query string
+	err := e.DB.QueryRow(`
+    SELECT COALESCE(usename, ''), COALESCE(datname, ''), COALESCE(query, '')
+    FROM pg_stat_activity WHERE pid = $1;`, pid).Scan(&user, &database, &query)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("no backend with process id %d", pid)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	action := "terminate the backend"
+	if cancelOnly {
+		action = "cancel the running query of"
+	}
+	if runes := []rune(query); len(runes) > processListQueryLength {
+		query = string(runes[:processListQueryLength]) + "..."
+	}
+	warning := fmt.Sprintf("WARNING: This will %s process %d (%s@%s).\nQuery: %s",
+		action, pid, user, database, strings.Join(strings.Fields(query), " "))
+	if !confirm(warning) {
+		return "", fmt.Errorf("Safe choice. Kill cancelled")
+	}
+
+	e.finalSQL = backendSignalSQL(pid, cancelOnly)
+	if err := sendBackendSignal(e.DB, pid, cancelOnly); err != nil {
+		return "", err
+	}
+	if cancelOnly {
+		return fmt.Sprintf("Query cancelled on process %d", pid), nil
+	}
+	return fmt.Sprintf("Process %d terminated", pid), nil
+}
+
+// sendBackendSignal cancels the running query of the backend (pg_cancel_backend)
+// or terminates it (pg_terminate_backend) without asking for confirmation.
+func sendBackendSignal(db *sql.DB, pid int, cancelOnly bool) error {
+	var signalled bool
+	if err := db.QueryRow(backendSignalSQL(pid, cancelOnly)).Scan(&signalled); err != nil {
+		return err
+	}
+	if !signalled {
+		return fmt.Errorf("process %d could not be signalled", pid)
+	}
+	return nil
+}
+
+// backendSignalSQL returns the statement sendBackendSignal runs, as recorded in
+// the audit log.
+func backendSignalSQL(pid int, cancelOnly bool) string {
+	function := "pg_terminate_backend"
+	if cancelOnly {
+		function = "pg_cancel_backend"
+	}
+	return fmt.Sprintf("SELECT %s(%d);", function, pid)
+}
@@ -0,0 +1,101 @@
+package pgterm
+
+// indexAdviceSQL flags unused, duplicate, prefix-redundant and invalid indexes and
+// foreign keys without a supporting index. Each finding carries the DDL that would
+// fix it; nothing is executed. $1 limits the report to a schema, an empty string
+// means all. Indexes that CREATE INDEX CONCURRENTLY is still building are invalid
+// too, so the invalid finding asks to check for a running build first.
+const indexAdviceSQL = `
+    WITH idx AS (
+        SELECT i.indexrelid, i.indrelid, i.indisunique, i.indisprimary, i.indisvalid,
+               i.indkey::text AS keys,
+               i.indclass::text AS classes,
+               i.indexprs IS NULL AND i.indpred IS NULL AS plain,
+               am.amname,
+               n.nspname AS schema_name,
+               t.relname AS table_name,
+               c.relname AS index_name,
+               pg_relation_size(i.indexrelid) AS size
+        FROM pg_index i
+        JOIN pg_class c ON c.oid = i.indexrelid
+        JOIN pg_class t ON t.oid = i.indrelid
+        JOIN pg_namespace n ON n.oid = t.relnamespace
+        JOIN pg_am am ON am.oid = c.relam
+        WHERE n.nspname !~ '^pg_' AND n.nspname <> 'information_schema'
+          AND ($1 = '' OR n.nspname = $1)
+    ), findings AS (
+        SELECT 'unused' AS finding,
+               format('%I.%I', idx.schema_name, idx.table_name) AS table_name,
+               idx.index_name,
+               idx.size,
+               'never scanned since statistics were last reset' AS detail,
+               format('DROP INDEX CONCURRENTLY %I.%I;', idx.schema_name, idx.index_name) AS ddl
+        FROM idx
+        JOIN pg_stat_user_indexes s ON s.indexrelid = idx.indexrelid
+        WHERE s.idx_scan = 0
+          AND idx.indisvalid AND NOT idx.indisunique AND NOT idx.indisprimary
+          AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = idx.indexrelid)
+        UNION ALL
+        SELECT * FROM (
+            SELECT DISTINCT ON (a.indexrelid)
+                   CASE WHEN a.keys = b.keys THEN 'duplicate' ELSE 'redundant' END AS finding,
+                   format('%I.%I', a.schema_name, a.table_name) AS table_name,
+                   a.index_name,
+                   a.size,
+                   CASE WHEN a.keys = b.keys THEN format('same columns as %I', b.index_name)
+                        ELSE format('leading columns of %I', b.index_name) END AS detail,
+                   format('DROP INDEX CONCURRENTLY %I.%I;', a.schema_name, a.index_name) AS ddl
+            FROM idx a
+            JOIN idx b ON b.indrelid = a.indrelid AND b.indexrelid <> a.indexrelid
+            WHERE a.amname = 'btree' AND b.amname = 'btree'
+              AND a.plain AND b.plain AND a.indisvalid AND b.indisvalid
+              AND NOT a.indisunique AND NOT a.indisprimary
+              AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = a.indexrelid)
+              AND ((a.keys = b.keys AND a.classes = b.classes
+                    AND (b.indisunique OR b.indisprimary OR a.indexrelid > b.indexrelid))
+                   OR (b.keys LIKE a.keys || ' %' AND b.classes LIKE a.classes || ' %'))
+            ORDER BY a.indexrelid, b.indisunique DESC, b.indexrelid
+        ) redundant
+        UNION ALL
+        SELECT 'invalid',
+               format('%I.%I', idx.schema_name, idx.table_name),
+               idx.index_name,
+               idx.size,
+               'failed CREATE INDEX CONCURRENTLY, or one still running: check SHOW PROGRESS before dropping',
+               format('DROP INDEX CONCURRENTLY %I.%I; %s;', idx.schema_name, idx.index_name,
+                      regexp_replace(pg_get_indexdef(idx.indexrelid), '^CREATE (UNIQUE )?INDEX', 'CREATE \1INDEX CONCURRENTLY'))
+        FROM idx
+        WHERE NOT idx.indisvalid
+        UNION ALL
+        SELECT 'missing fk index',
+               format('%I.%I', n.nspname, t.relname),
+               '',
+               NULL,
+               format('foreign key %I has no index on its columns', con.conname),
+               format('CREATE INDEX CONCURRENTLY ON %I.%I (%s);', n.nspname, t.relname,
+                      (SELECT string_agg(quote_ident(a.attname), ', ' ORDER BY k.ord)
+                       FROM unnest(con.conkey) WITH ORDINALITY k(attnum, ord)
+                       JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum))
+        FROM pg_constraint con
+        JOIN pg_class t ON t.oid = con.conrelid
+        JOIN pg_namespace n ON n.oid = t.relnamespace
+        WHERE con.contype = 'f'
+          AND n.nspname !~ '^pg_' AND n.nspname <> 'information_schema'
+          AND ($1 = '' OR n.nspname = $1)
+          AND NOT EXISTS (
+              SELECT 1 FROM pg_index i
+              WHERE i.indrelid = con.conrelid AND i.indpred IS NULL
+                AND (string_to_array(i.indkey::text, ' ')::int2[])[1:cardinality(con.conkey)] @> con.conkey
+                AND (string_to_array(i.indkey::text, ' ')::int2[])[1:cardinality(con.conkey)] <@ con.conkey)
+    )
+    SELECT finding, table_name, index_name,
+           COALESCE(pg_size_pretty(size), '') AS size,
+           detail, ddl
+    FROM findings
+    ORDER BY finding, findings.size DESC NULLS LAST, table_name, index_name;`
+
+// IndexAdvice renders the index findings for the schema, or all schemas when it
+// is empty. The suggested DDL is only printed, never executed.
+func (e *Executor) IndexAdvice(schema string) (string, error) {
+	return e.renderQuery(indexAdviceSQL, schema)
+}
@@ -0,0 +1,195 @@
+package pgterm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditConfig is the audit log section of the config file or of a profile.
+type AuditConfig struct {
+	// Enabled turns the audit log on.
+	Enabled bool `json:"enabled"`
+	// Path of the JSON-lines file, audit.jsonl next to the config file by default.
+	Path string `json:"path"`
+	// Redact replaces every string literal with '***', in the statements and in
+	// the values quoted by errors. Passwords and connection strings are always
+	// redacted.
+	Redact bool `json:"redact"`
+}
+
+// AuditLog appends a JSON line to a file for every statement that reaches
+// Executor.Execute.
+type AuditLog struct {
+	Target string // user@host:port/database of the connection
+	Redact bool   // replace string literals with '***'
+
+	mu     sync.Mutex
+	file   *os.File
+	osUser string
+}
+
+// auditEntry is one line of the audit log.
+type auditEntry struct {
+	Time        time.Time `json:"time"`
+	OSUser      string    `json:"os_user"`
+	Target      string    `json:"target"`
+	Environment string    `json:"environment,omitempty"`
+	Database    string    `json:"database"`
+	Schema      string    `json:"schema"`
+	Input       string    `json:"input"`
+	SQL         string    `json:"sql,omitempty"`
+	DurationMS  float64   `json:"duration_ms"`
+	Rows        int       `json:"rows"`
+	Error       string    `json:"error,omitempty"`
+	BreakGlass  bool      `json:"break_glass,omitempty"`
+}
+
+// auditLog receives the statements of the session, nil when auditing is off.
+var auditLog *AuditLog
+
+// AuditSettings returns the audit settings of the profile, falling back to the
+// audit section of the config file when the profile has none.
+func AuditSettings(profileName string) AuditConfig {
+	if profile, ok := config.Profiles[profileName]; ok && profile.Audit != nil {
+		return *profile.Audit
+	}
+	return config.Audit
+}
+
+// OpenAuditLog opens the audit log for appending. It returns nil when auditing
+// is not enabled.
+func OpenAuditLog(settings AuditConfig, target string) (*AuditLog, error) {
+	if !settings.Enabled {
+		return nil, nil
+	}
+	path := settings.Path
+	if path == "" {
+		configPath, err := ConfigPath()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(filepath.Dir(configPath), "audit.jsonl")
+	} else if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, rest)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	osUser := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		osUser = u.Username
+	}
+	return &AuditLog{Target: target, Redact: settings.Redact, file: file, osUser: osUser}, nil
+}
+
+// Close closes the audit log file.
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	return a.file.Close()
+}
+
+// Record appends an entry for a statement. input is what the user typed and sql
+// the final SQL sent to the server, empty for commands pgterm handles itself.
+// Failing to write is reported but does not stop the session.
+func (a *AuditLog) Record(input, sql string, duration time.Duration, rows int, err error) {
+	if a == nil {
+		return
+	}
+	entry := auditEntry{
+		Time:        time.Now().UTC(),
+		OSUser:      a.osUser,
+		Target:      a.Target,
+		Environment: session.GetEnvironment(),
+		Database:    session.GetDatabase(),
+		Schema:      session.GetSchema(),
+		Input:       redactSQL(input, a.Redact),
+		SQL:         redactSQL(sql, a.Redact),
+		DurationMS:  float64(duration.Microseconds()) / 1000,
+		Rows:        rows,
+		BreakGlass:  session.GetBreakGlass(),
+	}
+	if err != nil {
+		entry.Error = redactError(err.Error(), a.Redact, input, sql)
+	}
+	line, jsonErr := json.Marshal(entry)
+	if jsonErr != nil {
+		fmt.Printf("audit log: %s\n", jsonErr)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		fmt.Printf("audit log: %s\n", err)
+	}
+}
+
+// redactSQL replaces string literals with '***'. Literals following PASSWORD or
+// CONNECTION are always replaced, the others only when literals is set. Everything
+// between the literals is kept as written.
+func redactSQL(sql string, literals bool) string {
+	var b strings.Builder
+	last := 0
+	for _, t := range redactedLiterals(sql, literals) {
+		b.WriteString(sql[last:t.pos])
+		b.WriteString("'***'")
+		last = t.pos + len(t.text)
+	}
+	b.WriteString(sql[last:])
+	return b.String()
+}
+
+// redactedLiterals returns the string literals of the SQL that redactSQL replaces.
+func redactedLiterals(sql string, literals bool) []sqlToken {
+	tokens := lexSQL(sql)
+	var redacted []sqlToken
+	for i, t := range tokens {
+		if t.kind != tokenString {
+			continue
+		}
+		secret := i > 0 && (tokens[i-1].is("PASSWORD") || tokens[i-1].is("CONNECTION"))
+		if secret || literals {
+			redacted = append(redacted, t)
+		}
+	}
+	return redacted
+}
+
+// quotedValue matches the value a server error quotes at its end, e.g. the "abc"
+// of invalid input syntax for type integer: "abc".
+var quotedValue = regexp.MustCompile(`: "(?:[^"]|"")*"$`)
+
+// redactError removes the literals redactSQL replaces in the statements from an
+// error message, since the server echoes values back in quotes in messages such
+// as invalid input syntax for type integer: "abc". When literals is set any value
+// quoted at the end of the message is replaced as well, as it may be a literal
+// the server has converted, e.g. trimmed.
+func redactError(msg string, literals bool, statements ...string) string {
+	for _, sql := range statements {
+		for _, t := range redactedLiterals(sql, literals) {
+			value := unquoteString(t.text)
+			msg = strings.ReplaceAll(msg, `"`+value+`"`, `"***"`)
+			msg = strings.ReplaceAll(msg, "'"+value+"'", "'***'")
+		}
+	}
+	if literals {
+		msg = quotedValue.ReplaceAllString(msg, `: "***"`)
+	}
+	return msg
+}
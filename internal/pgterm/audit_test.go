@@ -0,0 +1,87 @@
+package pgterm
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRedactSQL(t *testing.T) {
+	tests := []struct {
+		sql      string
+		literals bool
+		want     string
+	}{
+		{"SELECT * FROM users WHERE email = 'a@b.c'", false, "SELECT * FROM users WHERE email = 'a@b.c'"},
+		{"SELECT * FROM users WHERE email = 'a@b.c'", true, "SELECT * FROM users WHERE email = '***'"},
+		{"ALTER ROLE app PASSWORD 'secret'", false, "ALTER ROLE app PASSWORD '***'"},
+		{"create user app with password 'it''s'", false, "create user app with password '***'"},
+		{"CREATE SUBSCRIPTION s CONNECTION 'host=db password=x' PUBLICATION p", false, "CREATE SUBSCRIPTION s CONNECTION '***' PUBLICATION p"},
+		{"ALTER ROLE app PASSWORD $$secret$$", false, "ALTER ROLE app PASSWORD '***'"},
+		{"SELECT E'a\\'b', $x$c$x$, 42", true, "SELECT '***', '***', 42"},
+		{"SELECT \"password\" FROM t -- 'comment'", true, "SELECT \"password\" FROM t -- 'comment'"},
+		{"SELECT 'a';\n  SELECT   'b'", true, "SELECT '***';\n  SELECT   '***'"},
+		{"", true, ""},
+	}
+	for _, tt := range tests {
+		if got := redactSQL(tt.sql, tt.literals); got != tt.want {
+			t.Errorf("redactSQL(%q, %v) = %q, want %q", tt.sql, tt.literals, got, tt.want)
+		}
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	tests := []struct {
+		msg      string
+		literals bool
+		sql      string
+		want     string
+	}{
+		{`pq: invalid input syntax for type integer: "abc"`, true, "SELECT 'abc'::int", `pq: invalid input syntax for type integer: "***"`},
+		{`pq: invalid input syntax for type integer: "abc"`, false, "SELECT 'abc'::int", `pq: invalid input syntax for type integer: "abc"`},
+		{`pq: invalid input syntax for type integer: " abc"`, true, "SELECT ' abc'::int", `pq: invalid input syntax for type integer: "***"`},
+		{`pq: invalid input syntax for type date: "2025-13-01" in 'x'`, true, "SELECT '2025-13-01'::date, 'x'", `pq: invalid input syntax for type date: "***" in '***'`},
+		{`pq: relation "orders" does not exist`, true, "SELECT * FROM orders WHERE a = 'b'", `pq: relation "orders" does not exist`},
+		{`pq: role "app" does not exist`, false, "ALTER ROLE app PASSWORD 'hunter2'", `pq: role "app" does not exist`},
+		{`pq: could not connect: "host=db password=hunter2"`, false, "CREATE SUBSCRIPTION s CONNECTION 'host=db password=hunter2' PUBLICATION p", `pq: could not connect: "***"`},
+	}
+	for _, tt := range tests {
+		if got := redactError(tt.msg, tt.literals, tt.sql); got != tt.want {
+			t.Errorf("redactError(%q, %v, %q) = %q, want %q", tt.msg, tt.literals, tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestRecordRedacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := OpenAuditLog(AuditConfig{Enabled: true, Path: path, Redact: true}, "app@db:5432/shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Record("SELECT 'abc'::int", "SELECT 'abc'::int", time.Millisecond, 0,
+		errors.New(`pq: invalid input syntax for type integer: "abc"`))
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry auditEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("audit line %q: %s", data, err)
+	}
+	if entry.Input != "SELECT '***'::int" || entry.SQL != "SELECT '***'::int" {
+		t.Errorf("recorded input %q and sql %q, want the literal redacted", entry.Input, entry.SQL)
+	}
+	if entry.Error != `pq: invalid input syntax for type integer: "***"` {
+		t.Errorf("recorded error %q, want the value redacted", entry.Error)
+	}
+	if entry.Target != "app@db:5432/shop" || entry.Rows != 0 {
+		t.Errorf("recorded target %q and rows %d", entry.Target, entry.Rows)
+	}
+}
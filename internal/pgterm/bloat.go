@@ -0,0 +1,233 @@
+package pgterm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// bloatEstimateSQL estimates table and btree index bloat from the planner
+// statistics, following the widely used queries from the pgsql-bloat-estimation
+// project. $1 is the schema and $2 an optional table name, empty for all tables.
+// Rows flagged "estimate (n/a)" have columns without statistics or of type name,
+// for which the estimation is known to be unreliable.
+const bloatEstimateSQL = `
+    WITH table_bloat AS (
+        SELECT schemaname, tblname, bs * tblpages AS real_size,
+               CASE WHEN tblpages - est_tblpages_ff > 0 THEN (tblpages - est_tblpages_ff) * bs ELSE 0 END AS bloat_size,
+               CASE WHEN tblpages > 0 AND tblpages - est_tblpages_ff > 0
+                    THEN 100 * (tblpages - est_tblpages_ff) / tblpages::float ELSE 0 END AS bloat_pct,
+               is_na
+        FROM (
+            SELECT ceil(reltuples / ((bs - page_hdr) * fillfactor / (tpl_size * 100))) + ceil(toasttuples / 4) AS est_tblpages_ff,
+                   tblpages, bs, schemaname, tblname, is_na
+            FROM (
+                SELECT (4 + tpl_hdr_size + tpl_data_size + (2 * ma)
+                        - CASE WHEN tpl_hdr_size % ma = 0 THEN ma ELSE tpl_hdr_size % ma END
+                        - CASE WHEN ceil(tpl_data_size)::int % ma = 0 THEN ma ELSE ceil(tpl_data_size)::int % ma END
+                       ) AS tpl_size,
+                       heappages + toastpages AS tblpages,
+                       reltuples, toasttuples, bs, page_hdr, schemaname, tblname, fillfactor, is_na
+                FROM (
+                    SELECT ns.nspname AS schemaname, tbl.relname AS tblname, tbl.reltuples,
+                           tbl.relpages AS heappages, COALESCE(toast.relpages, 0) AS toastpages,
+                           COALESCE(toast.reltuples, 0) AS toasttuples,
+                           COALESCE(substring(array_to_string(tbl.reloptions, ' ') FROM 'fillfactor=([0-9]+)')::smallint, 100) AS fillfactor,
+                           current_setting('block_size')::numeric AS bs,
+                           CASE WHEN version() ~ 'mingw32|64-bit|x86_64|ppc64|ia64|amd64|aarch64' THEN 8 ELSE 4 END AS ma,
+                           24 AS page_hdr,
+                           23 + CASE WHEN max(COALESCE(s.null_frac, 0)) > 0 THEN (7 + count(s.attname)) / 8 ELSE 0::int END AS tpl_hdr_size,
+                           sum((1 - COALESCE(s.null_frac, 0)) * COALESCE(s.avg_width, 0)) AS tpl_data_size,
+                           bool_or(att.atttypid = 'pg_catalog.name'::regtype)
+                               OR sum(CASE WHEN att.attnum > 0 THEN 1 ELSE 0 END) <> count(s.attname) AS is_na
+                    FROM pg_attribute att
+                    JOIN pg_class tbl ON att.attrelid = tbl.oid
+                    JOIN pg_namespace ns ON ns.oid = tbl.relnamespace
+                    LEFT JOIN pg_stats s ON s.schemaname = ns.nspname AND s.tablename = tbl.relname
+                                         AND s.inherited = false AND s.attname = att.attname
+                    LEFT JOIN pg_class toast ON tbl.reltoastrelid = toast.oid
+                    WHERE NOT att.attisdropped AND att.attnum > 0
+                      AND tbl.relkind IN ('r', 'm')
+                      AND ns.nspname = $1 AND ($2 = '' OR tbl.relname = $2)
+                    GROUP BY 1, 2, 3, 4, 5, 6, 7, 8, 9
+                ) table_stats
+            ) table_sizes
+        ) table_estimates
+    ), index_bloat AS (
+        SELECT nspname AS schemaname, tblname, idxname, bs * relpages::bigint AS real_size,
+               CASE WHEN relpages > est_pages_ff THEN bs * (relpages - est_pages_ff) ELSE 0 END AS bloat_size,
+               CASE WHEN relpages > est_pages_ff THEN 100 * (relpages - est_pages_ff)::float / relpages ELSE 0 END AS bloat_pct,
+               is_na
+        FROM (
+            SELECT COALESCE(1 + ceil(reltuples / floor((bs - pageopqdata - pagehdr) * fillfactor / (100 * (4 + nulldatahdrwidth)::float))), 0) AS est_pages_ff,
+                   bs, nspname, tblname, idxname, relpages, is_na
+            FROM (
+                SELECT maxalign, bs, nspname, tblname, idxname, reltuples, relpages, fillfactor, pagehdr, pageopqdata, is_na,
+                       (index_tuple_hdr_bm + maxalign
+                        - CASE WHEN index_tuple_hdr_bm % maxalign = 0 THEN maxalign ELSE index_tuple_hdr_bm % maxalign END
+                        + nulldatawidth + maxalign
+                        - CASE WHEN nulldatawidth = 0 THEN 0
+                               WHEN nulldatawidth::integer % maxalign = 0 THEN maxalign
+                               ELSE nulldatawidth::integer % maxalign END
+                       )::numeric AS nulldatahdrwidth
+                FROM (
+                    SELECT n.nspname, i.tblname, i.idxname, i.reltuples, i.relpages, i.fillfactor,
+                           current_setting('block_size')::numeric AS bs,
+                           CASE WHEN version() ~ 'mingw32|64-bit|x86_64|ppc64|ia64|amd64|aarch64' THEN 8 ELSE 4 END AS maxalign,
+                           24 AS pagehdr,
+                           16 AS pageopqdata,
+                           CASE WHEN max(COALESCE(s.null_frac, 0)) = 0 THEN 8 ELSE 8 + ((32 + 8 - 1) / 8) END AS index_tuple_hdr_bm,
+                           sum((1 - COALESCE(s.null_frac, 0)) * COALESCE(s.avg_width, 1024)) AS nulldatawidth,
+                           max(CASE WHEN i.atttypid = 'pg_catalog.name'::regtype THEN 1 ELSE 0 END) > 0 AS is_na
+                    FROM (
+                        SELECT ct.relname AS tblname, ct.relnamespace, ic.idxname, ic.reltuples, ic.relpages, ic.fillfactor,
+                               COALESCE(a1.attname, a2.attname) AS attname,
+                               COALESCE(a1.atttypid, a2.atttypid) AS atttypid,
+                               CASE WHEN a1.attnum IS NULL THEN ic.idxname ELSE ct.relname END AS attrelname
+                        FROM (
+                            SELECT ci.relname AS idxname, ci.reltuples, ci.relpages, i.indrelid AS tbloid, i.indexrelid AS idxoid,
+                                   COALESCE(substring(array_to_string(ci.reloptions, ' ') FROM 'fillfactor=([0-9]+)')::smallint, 90) AS fillfactor,
+                                   string_to_array(i.indkey::text, ' ')::int[] AS indkey,
+                                   generate_series(1, i.indnatts) AS attpos
+                            FROM pg_index i
+                            JOIN pg_class ci ON ci.oid = i.indexrelid
+                            JOIN pg_class t ON t.oid = i.indrelid
+                            JOIN pg_namespace tn ON tn.oid = t.relnamespace
+                            WHERE ci.relam = (SELECT oid FROM pg_am WHERE amname = 'btree')
+                              AND ci.relpages > 0
+                              AND tn.nspname = $1 AND ($2 = '' OR t.relname = $2)
+                        ) ic
+                        JOIN pg_class ct ON ct.oid = ic.tbloid
+                        LEFT JOIN pg_attribute a1 ON ic.indkey[ic.attpos] <> 0 AND a1.attrelid = ic.tbloid AND a1.attnum = ic.indkey[ic.attpos]
+                        LEFT JOIN pg_attribute a2 ON ic.indkey[ic.attpos] = 0 AND a2.attrelid = ic.idxoid AND a2.attnum = ic.attpos
+                    ) i
+                    JOIN pg_namespace n ON n.oid = i.relnamespace
+                    JOIN pg_stats s ON s.schemaname = n.nspname AND s.tablename = i.attrelname AND s.attname = i.attname
+                    GROUP BY 1, 2, 3, 4, 5, 6
+                ) index_stats
+            ) index_sizes
+        ) index_estimates
+    )
+    SELECT kind, name, pg_size_pretty(real_size::bigint) AS size,
+           pg_size_pretty(wasted::bigint) AS wasted,
+           round(wasted_pct::numeric, 1) AS wasted_pct,
+           method
+    FROM (
+        SELECT 'table' AS kind, format('%I.%I', schemaname, tblname) AS name,
+               real_size, bloat_size AS wasted, bloat_pct AS wasted_pct,
+               CASE WHEN is_na THEN 'estimate (n/a)' ELSE 'estimate' END AS method
+        FROM table_bloat
+        UNION ALL
+        SELECT 'index', format('%I.%I', schemaname, idxname) || ' on ' || quote_ident(tblname),
+               real_size, bloat_size, bloat_pct,
+               CASE WHEN is_na THEN 'estimate (n/a)' ELSE 'estimate' END
+        FROM index_bloat
+    ) bloat
+    ORDER BY bloat.wasted DESC, name;`
+
+// bloatExactSQL measures the bloat of a table and its btree indexes with
+// pgstattuple. The %s placeholder is the quoted schema of the extension and $1
+// the table. Unlike the estimate this scans the whole relation.
+const bloatExactSQL = `
+    SELECT kind, name, pg_size_pretty(real_size) AS size,
+           pg_size_pretty(wasted) AS wasted,
+           round(wasted_pct::numeric, 1) AS wasted_pct,
+           method
+    FROM (
+        SELECT 'table' AS kind, $1::regclass::text AS name,
+               t.table_len AS real_size,
+               t.dead_tuple_len + t.free_space AS wasted,
+               t.dead_tuple_percent + t.free_percent AS wasted_pct,
+               'pgstattuple' AS method
+        FROM %[1]s.pgstattuple($1::regclass) t
+        UNION ALL
+        SELECT 'index', i.indexrelid::regclass::text || ' on ' || $1::regclass::text,
+               s.index_size,
+               CASE WHEN s.avg_leaf_density = 'NaN' THEN 0
+                    ELSE (s.index_size * (100 - s.avg_leaf_density) / 100)::bigint END,
+               CASE WHEN s.avg_leaf_density = 'NaN' THEN 0 ELSE 100 - s.avg_leaf_density END,
+               'pgstatindex'
+        FROM pg_index i
+        JOIN pg_class c ON c.oid = i.indexrelid
+        JOIN pg_am am ON am.oid = c.relam,
+             %[1]s.pgstatindex(i.indexrelid::regclass) s
+        WHERE i.indrelid = $1::regclass AND am.amname = 'btree'
+    ) bloat
+    ORDER BY bloat.wasted DESC, name;`
+
+// bloatPartitionsSQL is bloatExactSQL for a partitioned table. pgstattuple and
+// pgstatindex reject partitioned tables and indexes, so every leaf partition and
+// its btree indexes are measured instead.
+const bloatPartitionsSQL = `
+    WITH RECURSIVE tree AS (
+        SELECT $1::regclass::oid AS relid
+        UNION ALL
+        SELECT i.inhrelid FROM pg_inherits i JOIN tree ON i.inhparent = tree.relid
+    ), leaves AS (
+        SELECT c.oid FROM tree JOIN pg_class c ON c.oid = tree.relid WHERE c.relkind = 'r'
+    )
+    SELECT kind, name, pg_size_pretty(real_size) AS size,
+           pg_size_pretty(wasted) AS wasted,
+           round(wasted_pct::numeric, 1) AS wasted_pct,
+           method
+    FROM (
+        SELECT 'table' AS kind, l.oid::regclass::text AS name,
+               t.table_len AS real_size,
+               t.dead_tuple_len + t.free_space AS wasted,
+               t.dead_tuple_percent + t.free_percent AS wasted_pct,
+               'pgstattuple' AS method
+        FROM leaves l, %[1]s.pgstattuple(l.oid::regclass) t
+        UNION ALL
+        SELECT 'index', i.indexrelid::regclass::text || ' on ' || i.indrelid::regclass::text,
+               s.index_size,
+               CASE WHEN s.avg_leaf_density = 'NaN' THEN 0
+                    ELSE (s.index_size * (100 - s.avg_leaf_density) / 100)::bigint END,
+               CASE WHEN s.avg_leaf_density = 'NaN' THEN 0 ELSE 100 - s.avg_leaf_density END,
+               'pgstatindex'
+        FROM leaves l
+        JOIN pg_index i ON i.indrelid = l.oid
+        JOIN pg_class c ON c.oid = i.indexrelid
+        JOIN pg_am am ON am.oid = c.relam,
+             %[1]s.pgstatindex(i.indexrelid::regclass) s
+        WHERE am.amname = 'btree'
+    ) bloat
+    ORDER BY bloat.wasted DESC, name;`
+
+// showBloat renders the table and btree index bloat of the active schema, or of a
+// single table with FOR. Exact numbers from pgstattuple are used for a single
+// table when the extension is installed; otherwise the bloat is estimated. A
+// partitioned table is measured through its partitions, which needs pgstattuple.
+func (e *Executor) showBloat(args []string) (string, error) {
+	schema, table := session.GetSchema(), ""
+	if len(args) > 0 {
+		if len(args) < 2 || strings.ToUpper(args[0]) != "FOR" {
+			return "", fmt.Errorf("usage: SHOW BLOAT [FOR table]")
+		}
+		name, err := resolveTable(strings.Join(args[1:], " "))
+		if err != nil {
+			return "", err
+		}
+		schema, table = name.Schema, name.Name
+	}
+	if table != "" {
+		name := objectName{Schema: schema, Name: table}.String()
+		var partitioned bool
+		if err := e.DB.QueryRow("SELECT relkind = 'p' FROM pg_class WHERE oid = $1::regclass;", name).Scan(&partitioned); err != nil {
+			return "", err
+		}
+		extSchema, installed, err := e.extensionSchema("pgstattuple")
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case installed && partitioned:
+			return e.renderQuery(fmt.Sprintf(bloatPartitionsSQL, pq.QuoteIdentifier(extSchema)), name)
+		case installed:
+			return e.renderQuery(fmt.Sprintf(bloatExactSQL, pq.QuoteIdentifier(extSchema)), name)
+		case partitioned:
+			return "", fmt.Errorf("%s is partitioned: install pgstattuple to measure its partitions, or use SHOW BLOAT FOR on a partition", name)
+		}
+	}
+	return e.renderQuery(bloatEstimateSQL, schema, table)
+}
@@ -0,0 +1,192 @@
+package pgterm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// The catalog queries behind SHOW TABLES, SHOW CREATE TABLE and DESCRIBE. Names
+// are always bound as parameters, never formatted into the SQL.
+const (
+	showTablesSQL = `
+    SELECT tablename FROM pg_tables WHERE schemaname = $1 ORDER BY tablename;`
+
+	describeTableSQL = `
+    SELECT column_name, data_type, is_nullable
+    FROM information_schema.columns
+    WHERE table_schema = $1 AND table_name = $2
+    ORDER BY ordinal_position;`
+
+	showCreateTableSQL = `
+    SELECT 'CREATE TABLE ' || quote_ident(table_schema) || '.' || quote_ident(table_name) || E'\n(\n' ||
+           string_agg(
+               '  ' || quote_ident(column_name) || ' ' || data_type ||
+               CASE WHEN character_maximum_length IS NOT NULL
+                    THEN '(' || character_maximum_length || ')'
+                    ELSE ''
+               END ||
+               CASE WHEN is_nullable = 'NO' THEN ' NOT NULL' ELSE '' END,
+               E',\n' ORDER BY ordinal_position
+           ) || E'\n);' AS create_table
+    FROM information_schema.columns
+    WHERE table_schema = $1 AND table_name = $2
+    GROUP BY table_schema, table_name;`
+
+	relationExistsSQL = `
+    SELECT EXISTS (
+        SELECT 1 FROM pg_class c
+        JOIN pg_namespace n ON n.oid = c.relnamespace
+        WHERE n.nspname = $1 AND c.relname = $2);`
+
+	schemaExistsSQL = `
+    SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1);`
+)
+
+// objectName is a schema-qualified name resolved the way PostgreSQL resolves it.
+type objectName struct {
+	Schema string
+	Name   string
+}
+
+// String returns the name quoted where needed, e.g. public."OrderItems".
+func (n objectName) String() string {
+	return quoteIdent(n.Schema) + "." + quoteIdent(n.Name)
+}
+
+// parseName parses a name as typed by the user, e.g. orders, public.orders or
+// "Sales"."OrderItems". Unquoted parts are folded to lower case and quoted parts
+// kept as written. Anything but identifiers separated by dots is rejected.
+func parseName(input string) ([]string, error) {
+	input = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(input), ";"))
+	tokens := lexSQL(input)
+	var parts []string
+	for i, t := range tokens {
+		switch {
+		case i%2 == 1 && t.isPunct("."):
+		case i%2 == 0 && t.kind == tokenWord:
+			parts = append(parts, strings.ToLower(t.text))
+		case i%2 == 0 && t.kind == tokenQuoted && len(t.text) > 2 && strings.HasSuffix(t.text, `"`):
+			parts = append(parts, unquoteIdentifier(t.text))
+		default:
+			return nil, fmt.Errorf("invalid name: %s", input)
+		}
+	}
+	if len(parts) == 0 || len(tokens)%2 == 0 {
+		return nil, fmt.Errorf("invalid name: %s", input)
+	}
+	return parts, nil
+}
+
+// resolveTable parses a table name, using the active schema when it is not qualified.
+func resolveTable(input string) (objectName, error) {
+	parts, err := parseName(input)
+	if err != nil {
+		return objectName{}, err
+	}
+	switch len(parts) {
+	case 1:
+		return objectName{Schema: session.GetSchema(), Name: parts[0]}, nil
+	case 2:
+		return objectName{Schema: parts[0], Name: parts[1]}, nil
+	}
+	return objectName{}, fmt.Errorf("invalid table name: %s", input)
+}
+
+// resolveSchema parses a schema name.
+func resolveSchema(input string) (string, error) {
+	parts, err := parseName(input)
+	if err != nil {
+		return "", err
+	}
+	if len(parts) != 1 {
+		return "", fmt.Errorf("invalid schema name: %s", input)
+	}
+	return parts[0], nil
+}
+
+// plainIdentifier matches identifiers that need no quotes.
+var plainIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// quoteIdent quotes an identifier only when PostgreSQL would not read it back
+// unchanged, so everyday names stay readable in rewritten SQL.
+func quoteIdent(name string) string {
+	if plainIdentifier.MatchString(name) && !sqlKeywords[name] {
+		return name
+	}
+	return pq.QuoteIdentifier(name)
+}
+
+// requireRelation returns an error when the table, view or other relation does not exist.
+func (e *Executor) requireRelation(name objectName) error {
+	var exists bool
+	if err := e.DB.QueryRow(relationExistsSQL, name.Schema, name.Name).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("table %s does not exist", name)
+	}
+	return nil
+}
+
+// showTables renders the tables of the active schema.
+func (e *Executor) showTables() (string, error) {
+	return e.renderQuery(showTablesSQL, session.GetSchema())
+}
+
+// describeTable renders the columns of a table.
+func (e *Executor) describeTable(input string) (string, error) {
+	name, err := resolveTable(input)
+	if err != nil {
+		return "", err
+	}
+	if err := e.requireRelation(name); err != nil {
+		return "", err
+	}
+	return e.renderQuery(describeTableSQL, name.Schema, name.Name)
+}
+
+// showCreateTable renders a CREATE TABLE statement for a table.
+func (e *Executor) showCreateTable(input string) (string, error) {
+	name, err := resolveTable(input)
+	if err != nil {
+		return "", err
+	}
+	if err := e.requireRelation(name); err != nil {
+		return "", err
+	}
+	return e.renderQuery(showCreateTableSQL, name.Schema, name.Name)
+}
+
+// useSchema makes an existing schema the active one.
+func (e *Executor) useSchema(input string) (string, error) {
+	schema, err := resolveSchema(input)
+	if err != nil {
+		return "", err
+	}
+	var exists bool
+	if err := e.DB.QueryRow(schemaExistsSQL, schema).Scan(&exists); err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("schema %s does not exist", quoteIdent(schema))
+	}
+	session.SetSchema(schema)
+	return fmt.Sprintf("Schema changed to %s", schema), nil
+}
+
+// skipFields returns the input after its first n whitespace separated words, as
+// typed, so quoted names with spaces survive.
+func skipFields(input string, n int) string {
+	rest := strings.TrimSpace(input)
+	for i := 0; i < n; i++ {
+		idx := strings.IndexFunc(rest, isSpace)
+		if idx < 0 {
+			return ""
+		}
+		rest = strings.TrimSpace(rest[idx:])
+	}
+	return rest
+}
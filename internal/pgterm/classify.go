@@ -0,0 +1,666 @@
+package pgterm
+
+import (
+	"strings"
+)
+
+// tokenKind is the lexical class of a SQL token.
+type tokenKind int
+
+const (
+	tokenWord   tokenKind = iota // keyword or unquoted identifier
+	tokenQuoted                  // "quoted identifier"
+	tokenString                  // 'string', E'string', $$dollar quoted$$
+	tokenNumber                  // 42, 3.14
+	tokenParam                   // $1
+	tokenPunct                   // any other single character, e.g. ( ) , ; . =
+)
+
+// sqlToken is a token of a SQL statement with its byte offset in the input.
+type sqlToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// is reports whether the token is the given keyword, ignoring case.
+func (t sqlToken) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+// isPunct reports whether the token is the given punctuation character.
+func (t sqlToken) isPunct(p string) bool {
+	return t.kind == tokenPunct && t.text == p
+}
+
+// lexSQL splits SQL into tokens the way the PostgreSQL lexer does, so keywords
+// inside strings, quoted identifiers, dollar quotes and comments are never
+// mistaken for part of the statement. Comments and whitespace are dropped.
+func lexSQL(sql string) []sqlToken {
+	var tokens []sqlToken
+	i := 0
+	for i < len(sql) {
+		c := sql[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			// block comments nest in PostgreSQL
+			depth := 0
+			for i < len(sql) {
+				if strings.HasPrefix(sql[i:], "/*") {
+					depth++
+					i += 2
+				} else if strings.HasPrefix(sql[i:], "*/") {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					i++
+				}
+			}
+		case c == '\'':
+			i = scanString(sql, i, false)
+			tokens = append(tokens, sqlToken{tokenString, sql[start:i], start})
+		case c == '"':
+			i++
+			for i < len(sql) {
+				if sql[i] == '"' {
+					if i+1 < len(sql) && sql[i+1] == '"' {
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				i++
+			}
+			tokens = append(tokens, sqlToken{tokenQuoted, sql[start:i], start})
+		case c == '$':
+			if tag, ok := dollarTag(sql[i:]); ok {
+				end := strings.Index(sql[i+len(tag):], tag)
+				if end < 0 {
+					i = len(sql)
+				} else {
+					i += len(tag) + end + len(tag)
+				}
+				tokens = append(tokens, sqlToken{tokenString, sql[start:i], start})
+				break
+			}
+			i++
+			for i < len(sql) && isDigit(sql[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{tokenParam, sql[start:i], start})
+		case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+			for i < len(sql) && (isDigit(sql[i]) || sql[i] == '.' || sql[i] == '_') {
+				i++
+			}
+			if i < len(sql) && (sql[i] == 'e' || sql[i] == 'E') {
+				i++
+				if i < len(sql) && (sql[i] == '+' || sql[i] == '-') {
+					i++
+				}
+				for i < len(sql) && isDigit(sql[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, sqlToken{tokenNumber, sql[start:i], start})
+		case isWordStart(c):
+			for i < len(sql) && (isWordStart(sql[i]) || isDigit(sql[i]) || sql[i] == '$') {
+				i++
+			}
+			// E'...', B'...', X'...' and N'...' are string constants
+			if i-start == 1 && i < len(sql) && sql[i] == '\'' && strings.ContainsRune("eEbBxXnN", rune(c)) {
+				i = scanString(sql, i, c == 'e' || c == 'E')
+				tokens = append(tokens, sqlToken{tokenString, sql[start:i], start})
+				break
+			}
+			tokens = append(tokens, sqlToken{tokenWord, sql[start:i], start})
+		default:
+			i++
+			tokens = append(tokens, sqlToken{tokenPunct, sql[start:i], start})
+		}
+	}
+	return tokens
+}
+
+// scanString returns the offset after the string constant starting with the
+// quote at i. Quotes are escaped by doubling them, or with a backslash in
+// escape strings.
+func scanString(sql string, i int, escapes bool) int {
+	i++
+	for i < len(sql) {
+		switch {
+		case escapes && sql[i] == '\\':
+			i += 2
+			continue
+		case sql[i] == '\'':
+			if i+1 < len(sql) && sql[i+1] == '\'' {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return len(sql)
+}
+
+// dollarTag returns the opening tag of a dollar quoted string, e.g. $$ or $body$.
+func dollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '$':
+			return s[:i+1], true
+		case isWordStart(s[i]) || (i > 1 && isDigit(s[i])):
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// statement is the classification of one SQL statement: what it does and which
+// objects it acts on.
+type statement struct {
+	Verb       string       // main command after any WITH clause, e.g. DELETE
+	ObjectType string       // kind of object for DROP and ALTER, e.g. TABLE or MATERIALIZED VIEW
+	Targets    []string     // objects the statement acts on, as written
+	Columns    []string     // columns dropped by ALTER TABLE
+	Drops      bool         // ALTER TABLE only: drops a column or a constraint
+	Filtered   bool         // UPDATE and DELETE only: has a WHERE clause that is not always true
+	Returning  bool         // data-modifying statements only: has a RETURNING clause
+	ReadWrite  bool         // SET, BEGIN and START TRANSACTION only: asks for READ WRITE
+	SetConfig  []string     // settings changed with set_config(), empty when not a literal
+	Nested     []*statement // data-modifying statements in WITH, or the statement run by EXPLAIN ANALYZE
+	Text       string       // SQL of a statement returned by classifySQL, without the semicolon
+}
+
+// all returns the statement followed by all statements nested in it.
+func (s *statement) all() []*statement {
+	list := []*statement{s}
+	for _, n := range s.Nested {
+		list = append(list, n.all()...)
+	}
+	return list
+}
+
+// classifySQL splits the input into statements and classifies each of them.
+func classifySQL(sql string) []*statement {
+	var statements []*statement
+	for _, tokens := range splitStatements(lexSQL(sql)) {
+		s := classifyTokens(tokens)
+		last := tokens[len(tokens)-1]
+		s.Text = sql[tokens[0].pos : last.pos+len(last.text)]
+		statements = append(statements, s)
+	}
+	return statements
+}
+
+// splitStatements splits tokens at semicolons outside of parentheses and drops
+// empty statements.
+func splitStatements(tokens []sqlToken) [][]sqlToken {
+	var statements [][]sqlToken
+	depth, start := 0, 0
+	for i, t := range tokens {
+		switch {
+		case t.isPunct("(") || t.isPunct("["):
+			depth++
+		case t.isPunct(")") || t.isPunct("]"):
+			depth--
+		case t.isPunct(";") && depth <= 0:
+			if i > start {
+				statements = append(statements, tokens[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		statements = append(statements, tokens[start:])
+	}
+	return statements
+}
+
+// classifyTokens classifies the tokens of a single statement.
+func classifyTokens(tokens []sqlToken) *statement {
+	// a statement may be wrapped in parentheses, e.g. (SELECT 1)
+	for len(tokens) > 1 && tokens[0].isPunct("(") && closingParen(tokens, 0) == len(tokens)-1 {
+		tokens = tokens[1 : len(tokens)-1]
+	}
+	s := &statement{SetConfig: setConfigNames(tokens)}
+	if len(tokens) == 0 {
+		return s
+	}
+	if tokens[0].is("WITH") {
+		var nested []*statement
+		nested, tokens = skipWith(tokens)
+		defer func() { s.Nested = append(nested, s.Nested...) }()
+		if len(tokens) == 0 {
+			return s
+		}
+	}
+	s.Verb = strings.ToUpper(tokens[0].text)
+	rest := tokens[1:]
+	switch s.Verb {
+	case "DELETE":
+		// DELETE FROM [ONLY] name [*] [[AS] alias] [USING ...] [WHERE ...]
+		i := skipWords(rest, 0, "FROM", "ONLY")
+		if name, _ := readName(rest, i); name != "" {
+			s.Targets = []string{name}
+		}
+		s.Filtered = hasFilter(rest)
+	case "UPDATE":
+		// UPDATE [ONLY] name [*] [[AS] alias] SET ... [FROM ...] [WHERE ...]
+		i := skipWords(rest, 0, "ONLY")
+		if name, _ := readName(rest, i); name != "" {
+			s.Targets = []string{name}
+		}
+		s.Filtered = hasFilter(rest)
+	case "TRUNCATE":
+		// TRUNCATE [TABLE] [ONLY] name [*] [, ...] [RESTART IDENTITY] [CASCADE]
+		s.ObjectType = "TABLE"
+		s.Targets = readNameList(rest, skipWords(rest, 0, "TABLE"), "ONLY")
+	case "DROP":
+		// DROP <object type> [CONCURRENTLY] [IF EXISTS] name [, ...] [CASCADE | RESTRICT]
+		i := 0
+		var words []string
+		previous := ""
+		for i < len(rest) && rest[i].kind == tokenWord && isObjectTypeWord(rest[i].text, previous) {
+			previous = strings.ToUpper(rest[i].text)
+			words = append(words, previous)
+			i++
+		}
+		s.ObjectType = strings.Join(words, " ")
+		i = skipWords(rest, i, "CONCURRENTLY", "IF", "EXISTS")
+		s.Targets = readNameList(rest, i)
+	case "ALTER":
+		if len(rest) > 0 {
+			s.ObjectType = strings.ToUpper(rest[0].text)
+		}
+		if s.ObjectType == "TABLE" || s.ObjectType == "FOREIGN" {
+			// ALTER [FOREIGN] TABLE [IF EXISTS] [ONLY] name [*] action [, ...]
+			i := skipWords(rest, 0, "FOREIGN", "TABLE", "IF", "EXISTS", "ONLY")
+			name, i := readName(rest, i)
+			if name != "" {
+				s.Targets = []string{name}
+			}
+			if i < len(rest) && rest[i].isPunct("*") {
+				i++
+			}
+			s.Columns, s.Drops = dropActions(rest[i:])
+			s.ObjectType = "TABLE"
+		}
+	case "SET", "RESET":
+		// SET [SESSION | LOCAL] name { TO | = } value, RESET name
+		i := skipWords(rest, 0, "SESSION", "LOCAL")
+		if name, _ := readName(rest, i); name != "" {
+			s.Targets = []string{strings.Join(splitName(name), ".")}
+		}
+		s.ReadWrite = readWrite(rest)
+	case "BEGIN", "START":
+		s.ReadWrite = readWrite(rest)
+	case "EXPLAIN":
+		if inner, ok := explainAnalyzeTarget(rest); ok {
+			s.Nested = []*statement{classifyTokens(inner)}
+		}
+	}
+	if isDataModifying(s.Verb) {
+		s.Returning = hasTopLevel(rest, "RETURNING")
+	}
+	return s
+}
+
+// setConfigNames returns the settings passed to set_config() anywhere in the
+// tokens, including subqueries. A setting that is not a string literal is
+// returned as an empty string.
+func setConfigNames(tokens []sqlToken) []string {
+	var names []string
+	for i := 0; i+1 < len(tokens); i++ {
+		if !tokens[i].is("SET_CONFIG") || !tokens[i+1].isPunct("(") {
+			continue
+		}
+		name := ""
+		if i+2 < len(tokens) && tokens[i+2].kind == tokenString {
+			name = strings.ToLower(unquoteString(tokens[i+2].text))
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// unquoteString returns the value of a quoted or dollar-quoted string literal. Doubled
+// quotes are unescaped, backslash escapes are not resolved.
+func unquoteString(literal string) string {
+	if strings.HasPrefix(literal, "$") {
+		if end := strings.Index(literal[1:], "$"); end >= 0 {
+			tag := literal[:end+2]
+			return strings.TrimSuffix(strings.TrimPrefix(literal, tag), tag)
+		}
+	}
+	literal = strings.TrimLeft(literal, "eE")
+	literal = strings.TrimPrefix(literal, "'")
+	literal = strings.TrimSuffix(literal, "'")
+	return strings.ReplaceAll(literal, "''", "'")
+}
+
+// skipWith skips the WITH clause and returns the data-modifying statements of its
+// common table expressions and the tokens of the main statement.
+func skipWith(tokens []sqlToken) ([]*statement, []sqlToken) {
+	var nested []*statement
+	i := skipWords(tokens, 1, "RECURSIVE")
+	for i < len(tokens) {
+		// name [(columns)] AS [NOT] [MATERIALIZED] ( body )
+		for i < len(tokens) && !tokens[i].is("AS") {
+			if tokens[i].isPunct("(") {
+				i = closingParen(tokens, i)
+			}
+			i++
+		}
+		i = skipWords(tokens, i+1, "NOT", "MATERIALIZED")
+		if i >= len(tokens) || !tokens[i].isPunct("(") {
+			return nested, nil
+		}
+		end := closingParen(tokens, i)
+		if body := classifyTokens(tokens[i+1 : min(end, len(tokens))]); isDataModifying(body.Verb) || len(body.Nested) > 0 {
+			nested = append(nested, body)
+		}
+		i = end + 1
+		// SEARCH and CYCLE clauses of recursive queries are not needed here
+		if i < len(tokens) && tokens[i].isPunct(",") {
+			i++
+			continue
+		}
+		break
+	}
+	if i >= len(tokens) {
+		return nested, nil
+	}
+	return nested, tokens[i:]
+}
+
+// isDataModifying reports whether the verb changes rows.
+func isDataModifying(verb string) bool {
+	switch verb {
+	case "INSERT", "UPDATE", "DELETE", "MERGE":
+		return true
+	}
+	return false
+}
+
+// explainAnalyzeTarget returns the statement of EXPLAIN ANALYZE, which is really
+// executed. ok is false for a plain EXPLAIN.
+func explainAnalyzeTarget(tokens []sqlToken) ([]sqlToken, bool) {
+	analyze := false
+	i := 0
+	if i < len(tokens) && tokens[i].isPunct("(") {
+		end := closingParen(tokens, i)
+		for j := i + 1; j < end && j < len(tokens); j++ {
+			if tokens[j].is("ANALYZE") || tokens[j].is("ANALYSE") {
+				analyze = !(j+1 < end && (tokens[j+1].is("FALSE") || tokens[j+1].is("OFF") || tokens[j+1].text == "0"))
+			}
+		}
+		i = end + 1
+	}
+	for i < len(tokens) && (tokens[i].is("ANALYZE") || tokens[i].is("ANALYSE") || tokens[i].is("VERBOSE")) {
+		if !tokens[i].is("VERBOSE") {
+			analyze = true
+		}
+		i++
+	}
+	if !analyze || i >= len(tokens) {
+		return nil, false
+	}
+	return tokens[i:], true
+}
+
+// closingParen returns the index of the parenthesis closing the one at open, or
+// the last index when it is never closed.
+func closingParen(tokens []sqlToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch {
+		case tokens[i].isPunct("("):
+			depth++
+		case tokens[i].isPunct(")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens) - 1
+}
+
+// skipWords skips any of the keywords starting at i.
+func skipWords(tokens []sqlToken, i int, keywords ...string) int {
+	for i < len(tokens) {
+		skipped := false
+		for _, k := range keywords {
+			if tokens[i].is(k) {
+				skipped = true
+				break
+			}
+		}
+		if !skipped {
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+// readName reads a possibly qualified name such as public."Orders" starting at i
+// and returns it as written with the index after it.
+func readName(tokens []sqlToken, i int) (string, int) {
+	var b strings.Builder
+	for i < len(tokens) && (tokens[i].kind == tokenWord || tokens[i].kind == tokenQuoted) {
+		b.WriteString(tokens[i].text)
+		i++
+		if i+1 < len(tokens) && tokens[i].isPunct(".") {
+			b.WriteString(".")
+			i++
+			continue
+		}
+		break
+	}
+	return b.String(), i
+}
+
+// readNameList reads a comma separated list of names starting at i, skipping the
+// given keywords in front of each name.
+func readNameList(tokens []sqlToken, i int, keywords ...string) []string {
+	var names []string
+	for i < len(tokens) {
+		i = skipWords(tokens, i, keywords...)
+		name, next := readName(tokens, i)
+		if name == "" {
+			break
+		}
+		names = append(names, name)
+		i = next
+		if i < len(tokens) && tokens[i].isPunct("*") {
+			i++
+		}
+		if i >= len(tokens) || !tokens[i].isPunct(",") {
+			break
+		}
+		i++
+	}
+	return names
+}
+
+// objectTypeWords are the words that make up the object type of DROP, e.g.
+// MATERIALIZED VIEW or FOREIGN DATA WRAPPER.
+var objectTypeWords = map[string]bool{
+	"ACCESS": true, "METHOD": true, "AGGREGATE": true, "CAST": true, "COLLATION": true,
+	"CONVERSION": true, "DATABASE": true, "DOMAIN": true, "EVENT": true, "TRIGGER": true,
+	"EXTENSION": true, "FOREIGN": true, "DATA": true, "WRAPPER": true, "TABLE": true,
+	"FUNCTION": true, "GROUP": true, "INDEX": true, "LANGUAGE": true, "PROCEDURAL": true,
+	"MATERIALIZED": true, "VIEW": true, "OPERATOR": true, "CLASS": true, "FAMILY": true,
+	"OWNED": true, "POLICY": true, "PROCEDURE": true, "PUBLICATION": true, "ROLE": true,
+	"ROUTINE": true, "RULE": true, "SCHEMA": true, "SEQUENCE": true, "SERVER": true,
+	"STATISTICS": true, "SUBSCRIPTION": true, "TABLESPACE": true, "TEXT": true, "SEARCH": true,
+	"CONFIGURATION": true, "DICTIONARY": true, "PARSER": true, "TEMPLATE": true,
+	"TRANSFORM": true, "TYPE": true, "USER": true, "MAPPING": true,
+}
+
+// objectTypePrefixes are the object type words that are followed by more of the
+// type, e.g. MATERIALIZED in MATERIALIZED VIEW.
+var objectTypePrefixes = map[string]bool{
+	"ACCESS": true, "DATA": true, "EVENT": true, "FOREIGN": true, "MATERIALIZED": true,
+	"OPERATOR": true, "PROCEDURAL": true, "SEARCH": true, "TEXT": true, "USER": true,
+}
+
+// isObjectTypeWord reports whether word continues the object type of DROP after
+// the previous type word. Only prefixes such as MATERIALIZED continue the type, so
+// a table called "index" is not mistaken for part of it.
+func isObjectTypeWord(word, previous string) bool {
+	if previous != "" && !objectTypePrefixes[previous] {
+		return false
+	}
+	return objectTypeWords[strings.ToUpper(word)]
+}
+
+// readWrite reports whether the transaction mode READ WRITE is given.
+func readWrite(tokens []sqlToken) bool {
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].is("READ") && tokens[i+1].is("WRITE") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTopLevel reports whether the keyword appears outside of parentheses.
+func hasTopLevel(tokens []sqlToken, keyword string) bool {
+	depth := 0
+	for _, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth == 0 && t.is(keyword):
+			return true
+		}
+	}
+	return false
+}
+
+// hasFilter reports whether an UPDATE or DELETE has a WHERE clause at the top
+// level that can actually filter rows. WHERE true, WHERE 1 = 1 and the like count
+// as no filter at all.
+func hasFilter(tokens []sqlToken) bool {
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth == 0 && t.is("WHERE"):
+			end := len(tokens)
+			for j := i + 1; j < len(tokens); j++ {
+				if tokens[j].is("RETURNING") {
+					end = j
+					break
+				}
+			}
+			return !alwaysTrue(tokens[i+1 : end])
+		}
+	}
+	return false
+}
+
+// alwaysTrue reports whether a condition is trivially true, e.g. true, NOT false,
+// 1 = 1 or 'a' = 'a'.
+func alwaysTrue(cond []sqlToken) bool {
+	for len(cond) > 1 && cond[0].isPunct("(") && closingParen(cond, 0) == len(cond)-1 {
+		cond = cond[1 : len(cond)-1]
+	}
+	switch {
+	case len(cond) == 1:
+		return cond[0].is("TRUE") || cond[0].text == "'t'" || cond[0].text == "'true'"
+	case len(cond) == 2:
+		return cond[0].is("NOT") && cond[1].is("FALSE")
+	case len(cond) == 3:
+		constant := cond[0].kind == tokenNumber || cond[0].kind == tokenString
+		return constant && cond[1].isPunct("=") && cond[0].kind == cond[2].kind && cond[0].text == cond[2].text
+	}
+	return false
+}
+
+// dropActions returns the columns dropped by the actions of ALTER TABLE and
+// whether any action drops something, a column or a constraint. The COLUMN
+// keyword is optional, so any DROP that does not drop a constraint drops a
+// column; DROP DEFAULT and friends belong to ALTER COLUMN and are skipped.
+func dropActions(actions []sqlToken) ([]string, bool) {
+	var columns []string
+	drops := false
+	depth := 0
+	actionStart := true
+	for i := 0; i < len(actions); i++ {
+		t := actions[i]
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth == 0 && t.isPunct(","):
+			actionStart = true
+			continue
+		case depth == 0 && actionStart && t.is("DROP"):
+			drops = true
+			j := i + 1
+			if j < len(actions) && actions[j].is("CONSTRAINT") {
+				break
+			}
+			j = skipWords(actions, j, "COLUMN", "IF", "EXISTS")
+			if name, _ := readName(actions, j); name != "" {
+				columns = append(columns, name)
+			}
+		}
+		actionStart = false
+	}
+	return columns, drops
+}
+
+// splitName splits a possibly qualified name into its parts and resolves each
+// part the way PostgreSQL does: unquoted identifiers are folded to lower case,
+// quoted ones are kept as written without the quotes.
+func splitName(name string) []string {
+	var parts []string
+	for _, t := range lexSQL(name) {
+		switch t.kind {
+		case tokenWord:
+			parts = append(parts, strings.ToLower(t.text))
+		case tokenQuoted:
+			parts = append(parts, unquoteIdentifier(t.text))
+		}
+	}
+	return parts
+}
+
+// unquoteIdentifier removes the quotes of a quoted identifier and unescapes
+// doubled quotes inside it.
+func unquoteIdentifier(quoted string) string {
+	quoted = strings.TrimPrefix(quoted, `"`)
+	quoted = strings.TrimSuffix(quoted, `"`)
+	return strings.ReplaceAll(quoted, `""`, `"`)
+}
@@ -0,0 +1,130 @@
+package pgterm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifySQL(t *testing.T) {
+	tests := []struct {
+		sql        string
+		verb       string
+		objectType string
+		targets    []string
+		columns    []string
+		nested     []string // verbs of the nested statements, in order
+	}{
+		{sql: "SELECT * FROM orders", verb: "SELECT"},
+		{sql: "delete from orders where id = 1", verb: "DELETE", targets: []string{"orders"}},
+		{sql: "DELETE FROM ONLY public.\"Orders\" o", verb: "DELETE", targets: []string{`public."Orders"`}},
+		{sql: "UPDATE ONLY orders SET paid = true", verb: "UPDATE", targets: []string{"orders"}},
+		{sql: "TRUNCATE TABLE ONLY a, b *, sales.c", verb: "TRUNCATE", objectType: "TABLE", targets: []string{"a", "b", "sales.c"}},
+		{sql: "(SELECT 1)", verb: "SELECT"},
+		{sql: "SELECT 'DROP TABLE orders' -- DELETE FROM orders", verb: "SELECT"},
+
+		// DROP lists and multi-word object types
+		{sql: "DROP TABLE IF EXISTS a, public.b CASCADE", verb: "DROP", objectType: "TABLE", targets: []string{"a", "public.b"}},
+		{sql: "DROP MATERIALIZED VIEW CONCURRENTLY mv", verb: "DROP", objectType: "MATERIALIZED VIEW", targets: []string{"mv"}},
+		{sql: "DROP INDEX CONCURRENTLY idx", verb: "DROP", objectType: "INDEX", targets: []string{"idx"}},
+		{sql: "DROP TABLE index", verb: "DROP", objectType: "TABLE", targets: []string{"index"}},
+		{sql: "DROP FOREIGN DATA WRAPPER w", verb: "DROP", objectType: "FOREIGN DATA WRAPPER", targets: []string{"w"}},
+		{sql: "DROP SCHEMA billing, \"Sales\"", verb: "DROP", objectType: "SCHEMA", targets: []string{"billing", `"Sales"`}},
+
+		// ALTER TABLE ... DROP COLUMN, with and without the COLUMN keyword
+		{sql: "ALTER TABLE orders DROP COLUMN total", verb: "ALTER", objectType: "TABLE", targets: []string{"orders"}, columns: []string{"total"}},
+		{sql: "ALTER TABLE IF EXISTS ONLY orders DROP IF EXISTS a, DROP COLUMN b", verb: "ALTER", objectType: "TABLE", targets: []string{"orders"}, columns: []string{"a", "b"}},
+		{sql: "ALTER TABLE orders DROP CONSTRAINT orders_pkey", verb: "ALTER", objectType: "TABLE", targets: []string{"orders"}},
+		{sql: "ALTER TABLE orders ALTER COLUMN total DROP DEFAULT", verb: "ALTER", objectType: "TABLE", targets: []string{"orders"}},
+		{sql: "ALTER FOREIGN TABLE remote DROP c", verb: "ALTER", objectType: "TABLE", targets: []string{"remote"}, columns: []string{"c"}},
+
+		// statements nested in WITH and EXPLAIN ANALYZE
+		{sql: "WITH gone AS (DELETE FROM orders RETURNING *) SELECT count(*) FROM gone", verb: "SELECT", nested: []string{"DELETE"}},
+		{sql: "WITH a AS (SELECT 1), b AS MATERIALIZED (UPDATE t SET x = 1 RETURNING x) INSERT INTO log SELECT * FROM b", verb: "INSERT", targets: nil, nested: []string{"UPDATE"}},
+		{sql: "WITH RECURSIVE r(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM r) SELECT * FROM r", verb: "SELECT"},
+		{sql: "WITH outer_cte AS (WITH inner_cte AS (DELETE FROM t RETURNING *) SELECT * FROM inner_cte) SELECT 1", verb: "SELECT", nested: []string{"SELECT", "DELETE"}},
+		{sql: "EXPLAIN ANALYZE DELETE FROM orders", verb: "EXPLAIN", nested: []string{"DELETE"}},
+		{sql: "EXPLAIN (ANALYZE, BUFFERS) UPDATE orders SET x = 1", verb: "EXPLAIN", nested: []string{"UPDATE"}},
+		{sql: "EXPLAIN (ANALYZE false) DELETE FROM orders", verb: "EXPLAIN"},
+		{sql: "EXPLAIN DELETE FROM orders", verb: "EXPLAIN"},
+		{sql: "EXPLAIN ANALYZE WITH d AS (DELETE FROM t RETURNING 1) SELECT * FROM d", verb: "EXPLAIN", nested: []string{"SELECT", "DELETE"}},
+	}
+	for _, tt := range tests {
+		statements := classifySQL(tt.sql)
+		if len(statements) != 1 {
+			t.Errorf("classifySQL(%q) returned %d statements, want 1", tt.sql, len(statements))
+			continue
+		}
+		s := statements[0]
+		if s.Verb != tt.verb || s.ObjectType != tt.objectType {
+			t.Errorf("classifySQL(%q) = %s %q, want %s %q", tt.sql, s.Verb, s.ObjectType, tt.verb, tt.objectType)
+		}
+		if !reflect.DeepEqual(s.Targets, tt.targets) {
+			t.Errorf("classifySQL(%q) targets = %q, want %q", tt.sql, s.Targets, tt.targets)
+		}
+		if !reflect.DeepEqual(s.Columns, tt.columns) {
+			t.Errorf("classifySQL(%q) columns = %q, want %q", tt.sql, s.Columns, tt.columns)
+		}
+		var nested []string
+		for _, n := range s.all()[1:] {
+			nested = append(nested, n.Verb)
+		}
+		if !reflect.DeepEqual(nested, tt.nested) {
+			t.Errorf("classifySQL(%q) nested = %q, want %q", tt.sql, nested, tt.nested)
+		}
+	}
+}
+
+func TestClassifySQLSplitsStatements(t *testing.T) {
+	tests := []struct {
+		sql   string
+		verbs []string
+		texts []string
+	}{
+		{"SELECT 1; DELETE FROM t;", []string{"SELECT", "DELETE"}, []string{"SELECT 1", "DELETE FROM t"}},
+		{"SELECT ';'; ;; UPDATE t SET a = 1", []string{"SELECT", "UPDATE"}, []string{"SELECT ';'", "UPDATE t SET a = 1"}},
+		{"CREATE FUNCTION f() RETURNS void AS $$ DELETE FROM t; $$ LANGUAGE sql", []string{"CREATE"}, []string{"CREATE FUNCTION f() RETURNS void AS $$ DELETE FROM t; $$ LANGUAGE sql"}},
+		{"/* DROP TABLE t; */ SELECT 1", []string{"SELECT"}, []string{"SELECT 1"}},
+	}
+	for _, tt := range tests {
+		var verbs, texts []string
+		for _, s := range classifySQL(tt.sql) {
+			verbs = append(verbs, s.Verb)
+			texts = append(texts, s.Text)
+		}
+		if !reflect.DeepEqual(verbs, tt.verbs) || !reflect.DeepEqual(texts, tt.texts) {
+			t.Errorf("classifySQL(%q) = %q %q, want %q %q", tt.sql, verbs, texts, tt.verbs, tt.texts)
+		}
+	}
+}
+
+func TestFiltered(t *testing.T) {
+	tests := []struct {
+		sql      string
+		filtered bool
+	}{
+		{"DELETE FROM t", false},
+		{"DELETE FROM t WHERE id = 1", true},
+		{"DELETE FROM t WHERE true", false},
+		{"DELETE FROM t WHERE TRUE RETURNING *", false},
+		{"DELETE FROM t WHERE (true)", false},
+		{"DELETE FROM t WHERE NOT false", false},
+		{"DELETE FROM t WHERE 1 = 1", false},
+		{"DELETE FROM t WHERE 'a' = 'a'", false},
+		{"DELETE FROM t WHERE 't'", false},
+		{"DELETE FROM t WHERE 1 = 2", true},
+		{"DELETE FROM t WHERE 1 = '1'", true},
+		{"DELETE FROM t WHERE id IN (SELECT id FROM u WHERE true)", true},
+		{"DELETE FROM t USING (SELECT 1 FROM u WHERE x = 1) u", false},
+		{"UPDATE t SET a = 1", false},
+		{"UPDATE t SET a = 1 WHERE a <> 1", true},
+		{"UPDATE t SET a = 1 WHERE 1=1", false},
+		{"UPDATE t SET a = (SELECT b FROM u WHERE u.id = 1)", false},
+		{"UPDATE t SET a = 'WHERE id = 1'", false},
+	}
+	for _, tt := range tests {
+		s := classifySQL(tt.sql)[0]
+		if s.Filtered != tt.filtered {
+			t.Errorf("classifySQL(%q).Filtered = %v, want %v", tt.sql, s.Filtered, tt.filtered)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package pgterm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Config holds the user settings read from config.json in the pgterm directory of
+// the user's config directory, e.g. ~/.config/pgterm/config.json on Linux.
+type Config struct {
+	// Guard overrides the action of destructive statement guard rules, keyed by
+	// rule name. Actions are allow, confirm and deny.
+	Guard map[string]string `json:"guard"`
+	// ConfirmRows makes every INSERT, UPDATE, DELETE and MERGE that affects more
+	// rows than this ask before it is committed, even with a WHERE clause. 0 turns
+	// the check off.
+	ConfirmRows int `json:"confirm_rows"`
+	// DryRunSample is the number of changed rows shown in dry-run mode, fetched
+	// with RETURNING *. 0 shows none.
+	DryRunSample int `json:"dry_run_sample"`
+	// Profiles holds the connection profiles by name.
+	Profiles map[string]Profile `json:"profiles"`
+	// EnvironmentHosts labels connections without a profile environment by host,
+	// e.g. {"prod": ["*.prod.example.com"]}. Patterns use path.Match syntax.
+	EnvironmentHosts map[string][]string `json:"environment_hosts"`
+	// Audit configures the audit log of connections whose profile has no audit section.
+	Audit AuditConfig `json:"audit"`
+	// Protected lists the schemas and tables that cannot be dropped, truncated,
+	// altered with DROP or emptied by DELETE without --break-glass, as glob
+	// patterns such as billing, billing.* or public.payments.
+	Protected []string `json:"protected"`
+}
+
+// Profile is a named set of connection settings, used with --profile. Flags given
+// on the command line take precedence over the profile.
+type Profile struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Database string `json:"database"`
+	// ReadOnly opens every session of the profile in read-only mode.
+	ReadOnly bool `json:"read_only"`
+	// Environment labels the connection as dev, staging or prod.
+	Environment string `json:"environment"`
+	// Audit overrides the audit log settings for the profile.
+	Audit *AuditConfig `json:"audit"`
+}
+
+// config is the configuration of the running pgterm, empty until LoadConfig is called.
+var config = &Config{}
+
+// ConfigPath returns the path of the configuration file.
+func ConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pgterm", "config.json"), nil
+}
+
+// LoadConfig reads and validates the configuration file. A missing file leaves
+// the defaults in place.
+func LoadConfig() error {
+	file, err := ConfigPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c := &Config{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("%s: %s", file, err)
+	}
+	for name, action := range c.Guard {
+		if findGuardRule(name) == nil {
+			return fmt.Errorf("%s: unknown guard rule %q", file, name)
+		}
+		switch action {
+		case guardAllow, guardConfirm, guardDeny:
+		default:
+			return fmt.Errorf("%s: guard rule %s has invalid action %q, use allow, confirm or deny", file, name, action)
+		}
+	}
+	for name, profile := range c.Profiles {
+		if profile.Environment != "" {
+			env, err := ParseEnvironment(profile.Environment)
+			if err != nil {
+				return fmt.Errorf("%s: profile %s: %s", file, name, err)
+			}
+			profile.Environment = env
+			c.Profiles[name] = profile
+		}
+	}
+	hosts := map[string][]string{}
+	for name, patterns := range c.EnvironmentHosts {
+		env, err := ParseEnvironment(name)
+		if err != nil {
+			return fmt.Errorf("%s: environment_hosts: %s", file, err)
+		}
+		for _, pattern := range patterns {
+			if !validPattern(pattern) {
+				return fmt.Errorf("%s: environment_hosts: invalid pattern %q", file, pattern)
+			}
+		}
+		hosts[env] = append(hosts[env], patterns...)
+	}
+	c.EnvironmentHosts = hosts
+	for _, pattern := range c.Protected {
+		if !validPattern(pattern) {
+			return fmt.Errorf("%s: protected: invalid pattern %q", file, pattern)
+		}
+	}
+	if c.ConfirmRows < 0 || c.DryRunSample < 0 {
+		return fmt.Errorf("%s: confirm_rows and dry_run_sample cannot be negative", file)
+	}
+	config = c
+	return nil
+}
+
+// LookupProfile returns the connection profile with the given name.
+func LookupProfile(name string) (Profile, error) {
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q, profiles are defined in the config file", name)
+	}
+	return profile, nil
+}
+
+// validPattern reports whether a glob pattern of the config file is well formed.
+func validPattern(pattern string) bool {
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
@@ -3,8 +3,10 @@ package pgterm
 import (
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type Connection struct {
@@ -20,6 +22,12 @@ type Connection struct {
 	Database string
 	// SSLConfig defines TLS Credentials if required
 	SSLConfig SSLConfig
+	// Options are run-time parameters set when the session starts,
+	// e.g. default_transaction_read_only
+	Options map[string]string
+	// Notices prints the NOTICE and WARNING messages of the server as they arrive,
+	// for the interactive prompt
+	Notices bool
 }
 
 type SSLConfig struct {
@@ -51,29 +59,79 @@ func InitiateConnection(conn *Connection) (*sql.DB, error) {
 
 // Connect initiates a database connection
 func (c *Connection) Connect() (*sql.DB, error) {
-	psqlDsn := fmt.Sprintf("host=%s port=%d user=%s "+
-		"password=%s dbname=%s sslmode=disable",
-		c.Host, c.Port, c.Username, c.Password, c.Database)
-	db, err := sql.Open("postgres", psqlDsn)
-	if err != nil {
-		return nil, err
+	return openDB(c.plainDSN(), c.Notices)
+}
+
+func (c *Connection) ConnectWithSSL() (*sql.DB, error) {
+	return openDB(c.sslDSN(), c.Notices)
+}
+
+// readOnly returns a copy of the settings whose sessions start with
+// default_transaction_read_only on.
+func (c *Connection) readOnly() *Connection {
+	copied := *c
+	copied.Options = map[string]string{"default_transaction_read_only": "on"}
+	for key, value := range c.Options {
+		if key != "default_transaction_read_only" {
+			copied.Options[key] = value
+		}
 	}
-	if err := db.Ping(); err != nil {
-		return nil, err
+	return &copied
+}
+
+// dsn returns the connection string InitiateConnection would connect with.
+func (c *Connection) dsn() string {
+	if len(c.SSLConfig.SSLMode) <= 0 {
+		return c.plainDSN()
 	}
-	return db, nil
+	return c.sslDSN()
 }
 
-func (c *Connection) ConnectWithSSL() (*sql.DB, error) {
-	psqlDsn := fmt.Sprintf("host=%s port=%d user=%s "+
+func (c *Connection) plainDSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s "+
+		"password=%s dbname=%s sslmode=disable",
+		c.Host, c.Port, c.Username, c.Password, c.Database) + c.options()
+}
+
+func (c *Connection) sslDSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s "+
 		"password=%s dbname=%s sslmode=%s sslcert=%s sslkey=%s sslrootca=%s",
-		c.Host, c.Port, c.Username, c.Password, c.Database, c.SSLConfig.SSLMode, c.SSLConfig.SSLCert, c.SSLConfig.SSLKey, c.SSLConfig.SSLRootCert)
-	db, err := sql.Open("postgres", psqlDsn)
+		c.Host, c.Port, c.Username, c.Password, c.Database, c.SSLConfig.SSLMode, c.SSLConfig.SSLCert, c.SSLConfig.SSLKey, c.SSLConfig.SSLRootCert) + c.options()
+}
+
+// openDB opens and checks a connection pool for the DSN. With notices, every
+// connection of the pool passes the NOTICE and WARNING messages of the server to
+// printNotice; otherwise they are dropped.
+func openDB(dsn string, notices bool) (*sql.DB, error) {
+	connector, err := pq.NewConnector(dsn)
 	if err != nil {
 		return nil, err
 	}
+	var db *sql.DB
+	if notices {
+		db = sql.OpenDB(pq.ConnectorWithNoticeHandler(connector, printNotice))
+	} else {
+		db = sql.OpenDB(connector)
+	}
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
 	return db, nil
 }
+
+// options formats the run-time parameters as DSN settings. lib/pq sends any
+// setting it does not know itself to the server when the session starts.
+func (c *Connection) options() string {
+	keys := make([]string, 0, len(c.Options))
+	for key := range c.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(c.Options[key])
+		fmt.Fprintf(&b, " %s='%s'", key, value)
+	}
+	return b.String()
+}
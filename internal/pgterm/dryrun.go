@@ -0,0 +1,168 @@
+package pgterm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// transactionalDML returns the statements of the SQL when they have to run
+// through runInTransaction, because dry-run mode is on or a confirm_rows limit
+// is set, and any of them modifies data: an INSERT, UPDATE, DELETE or MERGE, or
+// a statement with one of them in WITH or under EXPLAIN ANALYZE. Such a batch
+// may not control the transaction itself, since a COMMIT in it would commit the
+// changes before they are confirmed.
+func transactionalDML(sql string) ([]*statement, bool, error) {
+	if !session.GetDryRun() && config.ConfirmRows <= 0 {
+		return nil, false, nil
+	}
+	statements := classifySQL(sql)
+	if len(modifiedTargets(statements)) == 0 {
+		return nil, false, nil
+	}
+	for _, s := range statements {
+		if isTransactionControl(s.Verb) {
+			return nil, false, fmt.Errorf("%s cannot be combined with data changes in dry-run mode or with a confirm_rows limit", s.Verb)
+		}
+	}
+	return statements, true, nil
+}
+
+// isTransactionControl reports whether the verb starts or ends a transaction.
+func isTransactionControl(verb string) bool {
+	switch verb {
+	case "BEGIN", "START", "COMMIT", "END", "ROLLBACK", "ABORT", "PREPARE":
+		return true
+	}
+	return false
+}
+
+// runInTransaction runs the statements in a single transaction and reports the
+// rows affected by each of them before anything is committed. In dry-run mode it
+// shows a sample of the changed rows and always asks whether to commit; otherwise
+// it only asks when more rows than confirm_rows are affected in total. The
+// changes of nested statements cannot be counted, so for them it shows the result
+// and always asks.
+func (e *Executor) runInTransaction(statements []*statement) (string, error) {
+	now := time.Now()
+	tx, err := e.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	dryRun := session.GetDryRun()
+	var uncounted []*statement
+	total := 0
+	for _, s := range statements {
+		counted := isDataModifying(s.Verb)
+		if len(modifiedTargets(s.Nested)) > 0 {
+			uncounted = append(uncounted, s)
+		}
+		var affected int
+		switch {
+		// the verb follows any WITH clause, so WITH ... SELECT returns rows too
+		case s.Returning || (!counted && returnsRows(s.Verb)):
+			// the rows were asked for, so show all of them
+			rows, err := tx.Query(s.Text)
+			if err != nil {
+				return "", err
+			}
+			affected, err = renderRows(e.out(), rows)
+			rows.Close()
+			if err != nil {
+				return "", err
+			}
+		case dryRun && config.DryRunSample > 0 && counted && s.Verb != "MERGE":
+			rows, err := tx.Query(withReturning(s.Text))
+			if err != nil {
+				return "", err
+			}
+			affected, err = renderRowsLimit(e.out(), rows, config.DryRunSample)
+			rows.Close()
+			if err != nil {
+				return "", err
+			}
+			if affected > config.DryRunSample {
+				fmt.Fprintf(e.out(), "(showing %d of %d changed rows)\n", config.DryRunSample, affected)
+			}
+		default:
+			res, err := tx.Exec(s.Text)
+			if err != nil {
+				return "", err
+			}
+			n, _ := res.RowsAffected()
+			affected = int(n)
+		}
+		if counted {
+			total += affected
+			if len(statements) > 1 {
+				fmt.Fprintf(e.out(), "%s: %d rows affected\n", s.Verb, affected)
+			}
+		}
+	}
+	e.rowCount = total
+	elapsed := time.Since(now).Seconds()
+
+	overLimit := config.ConfirmRows > 0 && total > config.ConfirmRows
+	if len(uncounted) > 0 {
+		if !confirmUncounted(uncounted, elapsed) {
+			return "", fmt.Errorf("Rolled back, no rows were changed")
+		}
+	} else if dryRun || overLimit {
+		warning := fmt.Sprintf("Dry run: %d rows affected (%.3f Sec).", total, elapsed)
+		if overLimit {
+			warning = fmt.Sprintf("WARNING: %d rows affected, more than the confirm_rows limit of %d.", total, config.ConfirmRows)
+		}
+		if !confirmCommit(warning) {
+			return "", fmt.Errorf("Rolled back, no rows were changed")
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	if len(statements) == 1 && !isDataModifying(statements[0].Verb) {
+		return "", nil
+	}
+	return fmt.Sprintf("%d rows affected\n", total), nil
+}
+
+// confirmUncounted asks whether to commit changes whose rows cannot be counted,
+// such as those of a statement in WITH or under EXPLAIN ANALYZE. Since they cannot
+// be compared with confirm_rows it always asks, in dry-run mode or with a limit set.
+func confirmUncounted(statements []*statement, elapsed float64) bool {
+	targets := strings.Join(modifiedTargets(statements), ", ")
+	warning := fmt.Sprintf("WARNING: the statement changes data in %s, the rows cannot be counted against the confirm_rows limit.", targets)
+	if session.GetDryRun() {
+		warning = fmt.Sprintf("Dry run: the statement changes data in %s (%.3f Sec).", targets, elapsed)
+	}
+	return confirmCommit(warning)
+}
+
+// modifiedTargets returns the tables changed by the data-modifying statements
+// among the statements and the statements nested in them.
+func modifiedTargets(statements []*statement) []string {
+	var targets []string
+	for _, s := range statements {
+		for _, n := range s.all() {
+			if isDataModifying(n.Verb) {
+				targets = append(targets, targetsOrUnknown(n)...)
+			}
+		}
+	}
+	return targets
+}
+
+// withReturning appends RETURNING * to the statement, after its last token so a
+// trailing semicolon or comment does not swallow it.
+func withReturning(query string) string {
+	tokens := lexSQL(query)
+	for len(tokens) > 0 && tokens[len(tokens)-1].isPunct(";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return query
+	}
+	last := tokens[len(tokens)-1]
+	return strings.TrimSpace(query[:last.pos+len(last.text)]) + " RETURNING *"
+}
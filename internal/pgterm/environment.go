@@ -0,0 +1,99 @@
+package pgterm
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+)
+
+// Environment labels of a connection.
+const (
+	EnvDev     = "dev"
+	EnvStaging = "staging"
+	EnvProd    = "prod"
+)
+
+// environmentAliases maps the accepted spellings to the environment labels.
+var environmentAliases = map[string]string{
+	"dev": EnvDev, "development": EnvDev, "local": EnvDev,
+	"staging": EnvStaging, "stage": EnvStaging, "test": EnvStaging,
+	"prod": EnvProd, "production": EnvProd, "live": EnvProd,
+}
+
+// ParseEnvironment returns the environment label for a name such as production.
+func ParseEnvironment(name string) (string, error) {
+	env, ok := environmentAliases[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", fmt.Errorf("unknown environment %q, use dev, staging or prod", name)
+	}
+	return env, nil
+}
+
+// DetectEnvironment matches the host against the environment_hosts patterns of
+// the config, most sensitive environment first, and returns the label or "" when
+// none match.
+func DetectEnvironment(host string) string {
+	for _, env := range []string{EnvProd, EnvStaging, EnvDev} {
+		for _, pattern := range config.EnvironmentHosts[env] {
+			if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(host)); ok {
+				return env
+			}
+		}
+	}
+	return ""
+}
+
+// ApplicationName returns the application_name reported to the server, which
+// carries the environment label so it shows up in pg_stat_activity.
+func ApplicationName(env string) string {
+	if env == "" {
+		return "pgterm"
+	}
+	return fmt.Sprintf("pgterm (%s)", env)
+}
+
+// environmentColor returns the prompt color of the environment.
+func environmentColor(env string) prompt.Color {
+	switch env {
+	case EnvProd:
+		return prompt.Red
+	case EnvStaging:
+		return prompt.Yellow
+	}
+	return prompt.Green
+}
+
+// environmentBanner returns the line printed under the welcome message for a
+// labelled connection. Production gets a red banner.
+func environmentBanner(env string) string {
+	switch env {
+	case "":
+		return ""
+	case EnvProd:
+		return "\n" + whiteOnRed + boldText + " PRODUCTION: you are connected to a production database, changes are live " + resetText
+	case EnvStaging:
+		return "\n" + yellowText + "Environment: staging" + resetText
+	}
+	return "\n" + greenText + "Environment: " + env + resetText
+}
+
+// ddlVerbs are the statements that need an extra confirmation in production.
+var ddlVerbs = map[string]bool{
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "COMMENT": true,
+	"GRANT": true, "REVOKE": true, "REINDEX": true, "CLUSTER": true, "SECURITY": true,
+	"IMPORT": true, "REFRESH": true,
+}
+
+// checkProduction asks before any DDL runs in a production session.
+func checkProduction(s *statement) error {
+	if session.GetEnvironment() != EnvProd || !ddlVerbs[s.Verb] {
+		return nil
+	}
+	warning := fmt.Sprintf("WARNING: You are about to run %s on PRODUCTION.", strings.TrimSpace(s.Verb+" "+s.ObjectType))
+	if !confirm(redText + boldText + warning + resetText) {
+		return fmt.Errorf("Safe choice. Query cancelled")
+	}
+	return nil
+}
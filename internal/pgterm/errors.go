@@ -0,0 +1,141 @@
+package pgterm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// formatError describes a failed statement. Server errors are shown the way psql
+// shows them, with the SQLSTATE, the detail, hint and context fields and a caret
+// under the error position in what the user typed. sent is the SQL that was sent
+// to the server, which differs from input when addSchema rewrote it. It is empty
+// for builtins, whose errors come from their own queries, so no caret is drawn.
+func formatError(err error, input, sent string) string {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err.Error()
+	}
+	var b strings.Builder
+	severity := pqErr.Severity
+	if severity == "" {
+		severity = "ERROR"
+	}
+	fmt.Fprintf(&b, "%s%s:  %s%s (SQLSTATE %s %s)\n", redText, severity, pqErr.Message, resetText, pqErr.Code, pqErr.Code.Name())
+	if position, err := strconv.Atoi(pqErr.Position); err == nil && position > 0 && sent != "" {
+		if sent != input {
+			position = mapPosition(input, sent, position)
+		}
+		if position > 0 {
+			b.WriteString(caretLine(input, position))
+		}
+	}
+	if position, err := strconv.Atoi(pqErr.InternalPosition); err == nil && position > 0 && pqErr.InternalQuery != "" {
+		b.WriteString("QUERY:  " + strings.TrimPrefix(caretLine(pqErr.InternalQuery, position), "LINE 1: "))
+	}
+	for _, field := range []struct{ label, value string }{
+		{"DETAIL", pqErr.Detail},
+		{"HINT", pqErr.Hint},
+		{"CONTEXT", pqErr.Where},
+		{"SCHEMA NAME", pqErr.Schema},
+		{"TABLE NAME", pqErr.Table},
+		{"COLUMN NAME", pqErr.Column},
+		{"DATATYPE NAME", pqErr.DataTypeName},
+		{"CONSTRAINT NAME", pqErr.Constraint},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(&b, "%s:  %s\n", field.label, field.value)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// caretLine returns the line of the query holding the 1-based character position,
+// prefixed with LINE n:, and a caret under the position on the next line.
+func caretLine(query string, position int) string {
+	runes := []rune(query)
+	if position > len(runes) {
+		position = len(runes)
+	}
+	index := position - 1
+	lineStart := 0
+	lineNumber := 1
+	for i := 0; i < index && i < len(runes); i++ {
+		if runes[i] == '\n' {
+			lineStart = i + 1
+			lineNumber++
+		}
+	}
+	lineEnd := len(runes)
+	for i := lineStart; i < len(runes); i++ {
+		if runes[i] == '\n' {
+			lineEnd = i
+			break
+		}
+	}
+	prefix := fmt.Sprintf("LINE %d: ", lineNumber)
+	var padding strings.Builder
+	padding.WriteString(strings.Repeat(" ", utf8.RuneCountInString(prefix)))
+	for _, r := range runes[lineStart:max(index, lineStart)] {
+		// keep tabs so the caret lines up with the query as the terminal draws it
+		if r == '\t' {
+			padding.WriteRune('\t')
+		} else {
+			padding.WriteRune(' ')
+		}
+	}
+	return prefix + string(runes[lineStart:lineEnd]) + "\n" + padding.String() + "^\n"
+}
+
+// mapPosition maps a 1-based character position in the SQL produced by addSchema
+// back to the input it was produced from. addSchema keeps the tokens of the input
+// in order and only inserts schema. in front of some names, so the two token lists
+// are matched up one by one, skipping the inserted ones. A position in an inserted
+// prefix maps to the name it qualifies. It returns 0 when the two cannot be
+// matched up.
+func mapPosition(input, rewritten string, position int) int {
+	in, out := lexSQL(input), lexSQL(rewritten)
+	index := len(string([]rune(rewritten)[:min(max(position-1, 0), utf8.RuneCountInString(rewritten))]))
+	// characterAt converts a byte offset in the input to a 1-based position
+	characterAt := func(offset int) int {
+		return utf8.RuneCountInString(input[:min(max(offset, 0), len(input))]) + 1
+	}
+	i := 0
+	for j := 0; j < len(out); j++ {
+		name := out[j].kind == tokenWord || out[j].kind == tokenQuoted
+		qualified := i+1 < len(in) && in[i+1].isPunct(".")
+		if name && j+1 < len(out) && out[j+1].isPunct(".") && i < len(in) && (!qualified || !strings.EqualFold(in[i].text, out[j].text)) {
+			// an inserted schema prefix
+			if index < out[j+1].pos+1 {
+				return characterAt(in[i].pos)
+			}
+			j++
+			continue
+		}
+		if i >= len(in) || !strings.EqualFold(in[i].text, out[j].text) {
+			return 0
+		}
+		switch {
+		case index < out[j].pos:
+			// the position is in the space before the token
+			return characterAt(in[i].pos)
+		case index < out[j].pos+len(out[j].text):
+			return characterAt(in[i].pos + index - out[j].pos)
+		}
+		i++
+	}
+	if i != len(in) {
+		return 0
+	}
+	// past the last token, e.g. an error at the end of the input
+	if len(in) == 0 {
+		return characterAt(index)
+	}
+	inEnd := in[len(in)-1].pos + len(in[len(in)-1].text)
+	outEnd := out[len(out)-1].pos + len(out[len(out)-1].text)
+	return characterAt(inEnd + index - outEnd)
+}
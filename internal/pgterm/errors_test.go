@@ -0,0 +1,90 @@
+package pgterm
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// position returns the 1-based character position of the first occurrence of sub.
+func position(t *testing.T, s, sub string) int {
+	t.Helper()
+	i := strings.Index(s, sub)
+	if i < 0 {
+		t.Fatalf("%q not found in %q", sub, s)
+	}
+	return utf8.RuneCountInString(s[:i]) + 1
+}
+
+func TestMapPosition(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		schema string
+		at     string // text the server points at, found in both input and rewritten
+	}{
+		{"single line", "select * from orders where totl > 1", "public", "totl"},
+		{"multi-line", "SELECT *\nFROM orders\nWHERE totl > 1", "public", "totl"},
+		{"repeated whitespace", "SELECT   *    FROM    orders     WHERE totl > 1", "public", "totl"},
+		{"tabs", "SELECT *\tFROM\torders\tWHERE\ttotl > 1", "public", "totl"},
+		{"inside the rewritten name", "SELECT * FROM ordrs WHERE x = 1", "sales", "rdrs"},
+		{"after several rewritten names", "SELECT * FROM a JOIN b ON a.id = b.id WHERE b.nme = 'x'", "sales", "nme"},
+		{"quoted schema", "SELECT * FROM orders\n  WHERE totl > 1", "My Schema", "totl"},
+		{"keywords capitalized", "select * from orders where totl > 1", "public", "totl"},
+		{"schema same as table", "SELECT * FROM sales WHERE totl > 1", "sales", "totl"},
+		{"already qualified", "SELECT * FROM sales.orders o JOIN items i ON i.x = o.y WHERE totl > 1", "public", "totl"},
+		{"literal and comment", "SELECT 'from x' -- from y\nFROM orders WHERE totl > 1", "public", "totl"},
+		{"multibyte characters", "SELECT 'héllo' FROM orders WHERE totl > 1", "public", "totl"},
+	}
+	for _, tt := range tests {
+		rewritten := addSchema(tt.input, tt.schema)
+		if rewritten == tt.input {
+			t.Errorf("%s: addSchema did not rewrite %q", tt.name, tt.input)
+			continue
+		}
+		want := position(t, tt.input, tt.at)
+		if got := mapPosition(tt.input, rewritten, position(t, rewritten, tt.at)); got != want {
+			t.Errorf("%s: mapPosition(%q, %q) = %d, want %d", tt.name, tt.input, rewritten, got, want)
+		}
+	}
+}
+
+func TestMapPositionSchemaPrefix(t *testing.T) {
+	input := "SELECT * FROM\n  ordrs"
+	rewritten := addSchema(input, "public")
+	// the server points at the start of public.ordrs, which is ordrs in the input
+	if got, want := mapPosition(input, rewritten, position(t, rewritten, "public.")), position(t, input, "ordrs"); got != want {
+		t.Errorf("mapPosition in the schema prefix = %d, want %d", got, want)
+	}
+	// syntax errors at the end of the input point one past the last character
+	input = "SELECT * FROM orders WHERE"
+	rewritten = addSchema(input, "public")
+	if got, want := mapPosition(input, rewritten, utf8.RuneCountInString(rewritten)+1), utf8.RuneCountInString(input)+1; got != want {
+		t.Errorf("mapPosition at the end = %d, want %d", got, want)
+	}
+	// SQL that was not produced by addSchema cannot be matched up
+	if got := mapPosition("SELECT 1", "SELECT 2", 8); got != 0 {
+		t.Errorf("mapPosition of unrelated SQL = %d, want 0", got)
+	}
+}
+
+func TestCaretLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		position int
+		want     string
+	}{
+		{"first line", "SELECT x FROM t", 8, "LINE 1: SELECT x FROM t\n               ^\n"},
+		{"later line", "SELECT *\nFROM t\nWHERE y = 1", 23, "LINE 3: WHERE y = 1\n              ^\n"},
+		{"start of a line", "SELECT *\nFROM t", 10, "LINE 2: FROM t\n        ^\n"},
+		{"tabs are kept", "SELECT\tx", 8, "LINE 1: SELECT\tx\n              \t^\n"},
+		{"past the end", "SELECT", 7, "LINE 1: SELECT\n             ^\n"},
+		{"multibyte characters", "SELECT 'é', x", 13, "LINE 1: SELECT 'é', x\n                    ^\n"},
+	}
+	for _, tt := range tests {
+		if got := caretLine(tt.query, tt.position); got != tt.want {
+			t.Errorf("%s: caretLine(%q, %d) = %q, want %q", tt.name, tt.query, tt.position, got, tt.want)
+		}
+	}
+}
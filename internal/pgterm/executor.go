@@ -3,24 +3,35 @@ package pgterm
 import (
 	"database/sql"
 	"fmt"
-	"os"
+	"io"
 	"strings"
 	"time"
-
-	"github.com/olekukonko/tablewriter"
-	"github.com/olekukonko/tablewriter/renderer"
-	"github.com/olekukonko/tablewriter/tw"
 )
 
 // Executor is responsible for parsing and executing user input SQL/commands against the database.
 type Executor struct {
-	DB *sql.DB // Active database connection
+	DB  *sql.DB   // Active database connection
+	Out io.Writer // Where result tables are rendered, stdout when nil
+
+	rowCount int    // Rows returned or affected by the last statement
+	finalSQL string // SQL of the last statement after rewriting, empty for builtins
 }
 
 // Execute parses user input, rewrites SQL with schema (if needed), and executes it.
 // It handles both SQL commands and internal pseudo-commands like SHOW, USE, etc.
+// Every statement is recorded in the audit log when it is enabled.
 func (e *Executor) Execute(input string) (string, bool, error) {
+	start := time.Now()
+	resp, promptResetRequired, err := e.execute(input)
+	auditLog.Record(input, e.finalSQL, time.Since(start), e.rowCount, err)
+	return resp, promptResetRequired, err
+}
+
+// execute runs a statement for Execute.
+func (e *Executor) execute(input string) (string, bool, error) {
 	tokens := strings.Fields(input)
+	e.rowCount = 0
+	e.finalSQL = ""
 	if len(tokens) <= 0 {
 		return "", false, fmt.Errorf("unsupported command")
 	}
@@ -40,79 +51,36 @@ func (e *Executor) Execute(input string) (string, bool, error) {
 	if requireSanitization {
 		sql = addSchema(sql, session.ActiveSchema)
 	}
-	_, err = confirmIfNoWhere(sql)
+	e.finalSQL = sql
+	if err := e.checkStatement(sql); err != nil {
+		return "", promptResetRequired, err
+	}
+
+	// Data-modifying statements report their affected rows before committing
+	// in dry-run mode or when a confirm_rows limit is set.
+	statements, ok, err := transactionalDML(sql)
 	if err != nil {
 		return "", promptResetRequired, err
 	}
+	if ok {
+		resp, err := e.runInTransaction(statements)
+		return resp, promptResetRequired, err
+	}
 
 	// Execute the SQL query.
-	sqlFields := strings.Fields(sql)
-	if sqlFields[0] == "SELECT" {
-		now := time.Now()
-		rows, err := e.DB.Query(sql)
+	if returnsRows(sql) {
+		resp, err := e.renderQuery(sql)
 		if err != nil {
 			return "", promptResetRequired, err
 		}
-		timeDif := time.Since(now).Seconds()
-		defer rows.Close()
-		// Prepare the table writer for rendering output as markdown.
-		columns, _ := rows.Columns()
-		table := tablewriter.NewTable(os.Stdout, tablewriter.WithRenderer(renderer.NewMarkdown(
-			tw.Rendition{
-				Settings: tw.Settings{Separators: tw.Separators{BetweenRows: tw.On}},
-				Borders:  tw.Border{Top: tw.On, Bottom: tw.On},
-			},
-		)), tablewriter.WithConfig(tablewriter.Config{
-			Row: tw.CellConfig{
-				Alignment: tw.CellAlignment{Global: tw.AlignLeft}, // Left-align row data
-			},
-			Header: tw.CellConfig{
-				Formatting: tw.CellFormatting{AutoFormat: tw.Off},
-				Alignment:  tw.CellAlignment{Global: tw.AlignLeft},
-			},
-			Footer: tw.CellConfig{
-				Alignment: tw.CellAlignment{Global: tw.AlignRight},
-			},
-		}))
-
-		// Setup containers for scanning row values.
-		values := make([]interface{}, len(columns))
-		pointers := make([]interface{}, len(columns))
-		for i := range values {
-			pointers[i] = &values[i]
-		}
-
-		table.Header(columns)
-		rowCount := 0
-		// Read and format each row
-		for rows.Next() {
-			if err := rows.Scan(pointers...); err != nil {
-				return "", promptResetRequired, err
-			}
-			row := make([]string, len(columns))
-			for i, val := range values {
-				if val == nil {
-					row[i] = "NULL"
-				} else {
-					switch v := val.(type) {
-					case []byte:
-						row[i] = string(v)
-					default:
-						row[i] = fmt.Sprintf("%v", v)
-					}
-				}
-			}
-			rowCount++
-			table.Append(row)
-		}
-		table.Render()
-		return fmt.Sprintf("\n%d rows returned in set (%.3f Sec)", rowCount, timeDif), promptResetRequired, nil
+		return resp, promptResetRequired, nil
 	} else {
 		res, err := e.DB.Exec(sql)
 		if err != nil {
 			return "", promptResetRequired, err
 		}
 		affected, _ := res.RowsAffected()
+		e.rowCount = int(affected)
 		return fmt.Sprintf("%d rows affected\n", affected), promptResetRequired, nil
 	}
 }
@@ -125,6 +93,8 @@ func (e *Executor) intepretCommand(cmd string) (string, bool, bool, bool, error)
 		return "", false, false, false, fmt.Errorf("error: missing command")
 	}
 	mainCmd := strings.ToUpper(tokens[0])
+	// args holds the command words without the statement terminator.
+	args := strings.Fields(strings.TrimRight(cmd, "; \t\n"))
 
 	switch mainCmd {
 	case "SHOW":
@@ -132,60 +102,163 @@ func (e *Executor) intepretCommand(cmd string) (string, bool, bool, bool, error)
 			return "", false, false, false, fmt.Errorf("missing argument for SHOW")
 		}
 		subCmd := strings.TrimSuffix(strings.ToUpper(tokens[1]), ";")
+		if kind, used := objectKind(args[1:]); kind != "" {
+			msg, err := e.showObjects(kind, args[1+used:])
+			return msg, false, false, false, err
+		}
 		switch subCmd {
 		case "SCHEMAS", "schemas":
 			return "SELECT schema_name FROM information_schema.schemata;", true, false, false, nil
 		case "TABLES", "tables":
-			return fmt.Sprintf("SELECT tablename FROM pg_tables WHERE schemaname = '%s';", session.ActiveSchema), true, false, false, nil
+			msg, err := e.showTables()
+			return msg, false, false, false, err
+		case "PROCESSLIST":
+			msg, err := e.showProcessList(false)
+			return msg, false, false, false, err
+		case "FULL":
+			if len(args) > 2 && strings.ToUpper(args[2]) == "PROCESSLIST" {
+				msg, err := e.showProcessList(true)
+				return msg, false, false, false, err
+			}
+		case "INDEX":
+			if len(args) < 3 || strings.ToUpper(args[2]) != "ADVICE" {
+				return "", false, false, false, fmt.Errorf("missing argument for SHOW INDEX ADVICE")
+			}
+			filter, err := parseShowFilter(args[3:])
+			if err != nil {
+				return "", false, false, false, err
+			}
+			if filter.Schema == "" {
+				filter.Schema = session.GetSchema()
+			}
+			msg, err := e.IndexAdvice(filter.Schema)
+			return msg, false, false, false, err
+		case "REPLICATION":
+			msg, err := e.showReplication()
+			return msg, false, false, false, err
+		case "SLOTS":
+			msg, err := e.showSlots()
+			return msg, false, false, false, err
+		case "PROGRESS":
+			msg, err := e.showProgress()
+			return msg, false, false, false, err
+		case "BLOAT":
+			msg, err := e.showBloat(args[2:])
+			return msg, false, false, false, err
+		case "SLOW":
+			if len(args) < 3 || strings.ToUpper(args[2]) != "QUERIES" {
+				return "", false, false, false, fmt.Errorf("missing argument for SHOW SLOW QUERIES")
+			}
+			msg, err := e.parseSlowQueries(args[3:])
+			return msg, false, false, false, err
+		case "LOCKS":
+			msg, err := e.showLocks()
+			return msg, false, false, false, err
+		case "GRANTS", "ROLES", "PRIVILEGES":
+			msg, err := e.parseGrantsCommand(cmd, args[1:])
+			return msg, false, false, false, err
+		case "DATABASE", "SCHEMA":
+			if len(args) < 3 || strings.ToUpper(args[2]) != "SIZES" {
+				return "", false, false, false, fmt.Errorf("missing argument for SHOW %s SIZES", subCmd)
+			}
+			msg, err := e.showSizes(subCmd, args[3:])
+			return msg, false, false, false, err
+		case "TABLE":
+			if len(args) > 2 && strings.ToUpper(args[2]) == "SIZES" {
+				msg, err := e.showSizes(subCmd, args[3:])
+				return msg, false, false, false, err
+			}
+			if len(args) < 3 || strings.ToUpper(args[2]) != "STATUS" {
+				return "", false, false, false, fmt.Errorf("missing argument for SHOW TABLE STATUS")
+			}
+			filter, err := parseShowFilter(args[3:])
+			if err != nil {
+				return "", false, false, false, err
+			}
+			msg, err := e.showTableStatus(filter)
+			return msg, false, false, false, err
+		case "GLOBAL", "SESSION", "VARIABLES", "STATUS":
+			// GLOBAL and SESSION are accepted for MySQL compatibility and ignored.
+			rest := args[1:]
+			if subCmd == "GLOBAL" || subCmd == "SESSION" {
+				rest = args[2:]
+			}
+			if len(rest) == 0 {
+				return "", false, false, false, fmt.Errorf("missing argument for SHOW %s", subCmd)
+			}
+			filter, err := parseShowFilter(rest[1:])
+			if err != nil {
+				return "", false, false, false, err
+			}
+			var msg string
+			switch strings.ToUpper(rest[0]) {
+			case "VARIABLES":
+				msg, err = e.showVariables(filter)
+			case "STATUS":
+				msg, err = e.showGlobalStatus(filter)
+			default:
+				return "", false, false, false, fmt.Errorf("unsupported command: %s", cmd)
+			}
+			return msg, false, false, false, err
 		case "DATABASES", "databases":
-			return "SELECT datname FROM pg_database WHERE datistemplate = false;", true, false, false, nil
+			return showDatabasesSQL, true, false, false, nil
 		case "CREATE", "create":
-			if len(tokens) < 4 {
+			if len(tokens) < 4 || strings.ToUpper(tokens[2]) != "TABLE" {
 				return "", false, false, false, fmt.Errorf("missing argument for SHOW CREATE TABLE")
 			}
-			table := strings.TrimSuffix(tokens[3], ";")
-			return fmt.Sprintf(`
-        SELECT 'CREATE TABLE ' || relname || E'\n(\n' ||
-        string_agg(
-          '  ' || column_name || ' ' || data_type ||
-          CASE WHEN character_maximum_length IS NOT NULL
-               THEN '(' || character_maximum_length || ')'
-          ELSE ''
-          END ||
-          CASE WHEN is_nullable = 'NO' THEN ' NOT NULL' ELSE '' END,
-          E',\n'
-        )
-        || E'\n);' as create_table
-        FROM information_schema.columns c
-        JOIN pg_class t ON c.table_name = t.relname
-        WHERE table_schema = '%s'
-          AND table_name = '%s'
-        GROUP BY relname;
-    `, session.ActiveSchema, table), true, false, false, nil
+			msg, err := e.showCreateTable(skipFields(cmd, 3))
+			return msg, false, false, false, err
+		default:
+			// Anything else is a server setting, e.g. SHOW work_mem;
+			return cmd, true, false, false, nil
 		}
 
 	case "DESCRIBE", "DESC":
 		if len(tokens) < 2 {
 			return "", false, false, false, fmt.Errorf("DESCRIBE needs a table name")
 		}
-		table := strings.TrimSuffix(tokens[1], ";")
-		return fmt.Sprintf(`
-            SELECT column_name, data_type, is_nullable
-            FROM information_schema.columns
-            WHERE table_schema = '%s' AND table_name = '%s';`, session.GetSchema(), table), true, false, false, nil
+		msg, err := e.describeTable(skipFields(cmd, 1))
+		return msg, false, false, false, err
 	case "USE", "use":
 		if len(tokens) < 3 {
 			return "", false, false, false, fmt.Errorf("missing argument for USE")
 		}
 		subCmd := strings.ToUpper(tokens[1])
-		name := strings.TrimSuffix(strings.ToUpper(tokens[2]), ";")
 		switch subCmd {
 		case "SCHEMA":
-			session.SetSchema(strings.ToLower(name))
-			return fmt.Sprintf("Schema changed to %s", session.ActiveSchema), false, false, true, nil
+			msg, err := e.useSchema(skipFields(cmd, 2))
+			return msg, false, false, err == nil, err
 		default:
 			return "", false, false, false, fmt.Errorf("Missing argument for USE")
 		}
+	case "EXPLAIN":
+		if len(args) > 1 && strings.ToUpper(args[1]) == "VISUAL" {
+			query, commit, err := parseExplainVisual(cmd)
+			if err != nil {
+				return "", false, false, false, err
+			}
+			query = addSchema(query, session.GetSchema())
+			if commit {
+				if err := e.checkStatement(query); err != nil {
+					return "", false, false, false, err
+				}
+			}
+			msg, err := e.explainVisual(query, commit)
+			return msg, false, false, false, err
+		}
+		return cmd, true, true, false, nil
+	case "RESET":
+		if len(args) == 3 && strings.ToUpper(args[1]) == "SLOW" && strings.ToUpper(args[2]) == "QUERIES" {
+			msg, err := e.resetSlowQueries()
+			return msg, false, false, false, err
+		}
+		return cmd, true, false, false, nil
+	case "KILL":
+		msg, err := e.kill(args)
+		return msg, false, false, false, err
+	case "LISTEN", "UNLISTEN":
+		msg, err := e.listen(cmd, mainCmd == "UNLISTEN")
+		return msg, false, false, false, err
 	case "GRANT":
 		return cmd, true, false, false, nil // pass through to database without adding schema
 	case "CREATE":
@@ -218,3 +291,17 @@ func (e *Executor) intepretCommand(cmd string) (string, bool, bool, bool, error)
 	}
 	return "", false, true, false, fmt.Errorf("unsupported command: %s", cmd)
 }
+
+// returnsRows reports whether the statement produces a result set and therefore
+// has to be run with Query instead of Exec.
+func returnsRows(sql string) bool {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "SHOW", "VALUES", "TABLE", "EXPLAIN":
+		return true
+	}
+	return false
+}
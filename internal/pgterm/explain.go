@@ -0,0 +1,271 @@
+package pgterm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// misestimateFactor is the ratio between estimated and actual rows above which
+// a plan node is flagged as misestimated.
+const misestimateFactor = 10
+
+// planNode is a node of EXPLAIN (FORMAT JSON) output.
+type planNode struct {
+	NodeType            string      `json:"Node Type"`
+	RelationName        string      `json:"Relation Name"`
+	Schema              string      `json:"Schema"`
+	Alias               string      `json:"Alias"`
+	IndexName           string      `json:"Index Name"`
+	JoinType            string      `json:"Join Type"`
+	Strategy            string      `json:"Strategy"`
+	ParentRelationship  string      `json:"Parent Relationship"`
+	PlanRows            float64     `json:"Plan Rows"`
+	ActualRows          float64     `json:"Actual Rows"`
+	ActualLoops         float64     `json:"Actual Loops"`
+	ActualTotalTime     float64     `json:"Actual Total Time"`
+	SharedHitBlocks     int64       `json:"Shared Hit Blocks"`
+	SharedReadBlocks    int64       `json:"Shared Read Blocks"`
+	Filter              string      `json:"Filter"`
+	IndexCond           string      `json:"Index Cond"`
+	HashCond            string      `json:"Hash Cond"`
+	MergeCond           string      `json:"Merge Cond"`
+	RowsRemovedByFilter float64     `json:"Rows Removed by Filter"`
+	Plans               []*planNode `json:"Plans"`
+
+	exclusive float64 // time spent in this node without its children, in ms
+}
+
+// explainResult is the top level object of EXPLAIN (ANALYZE, FORMAT JSON) output.
+type explainResult struct {
+	Plan          *planNode `json:"Plan"`
+	PlanningTime  float64   `json:"Planning Time"`
+	ExecutionTime float64   `json:"Execution Time"`
+}
+
+// parseExplainVisual splits EXPLAIN VISUAL [--commit] <query> into the query and
+// whether data changes should be committed.
+func parseExplainVisual(cmd string) (string, bool, error) {
+	// drop the EXPLAIN VISUAL words but keep the query text as typed
+	rest := strings.TrimSpace(cmd)
+	for i := 0; i < 2; i++ {
+		if idx := strings.IndexFunc(rest, isSpace); idx >= 0 {
+			rest = strings.TrimSpace(rest[idx:])
+		} else {
+			rest = ""
+		}
+	}
+	commit := false
+	if fields := strings.Fields(rest); len(fields) > 0 && len(fields[0]) > 2 && strings.HasPrefix(fields[0], "--") {
+		// --commit is an option only as a word of its own; anything else starting
+		// with -- is a typo rather than a comment in front of the query
+		if fields[0] != "--commit" {
+			return "", false, fmt.Errorf("unknown EXPLAIN VISUAL option %s, only --commit is supported", fields[0])
+		}
+		commit = true
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, "--commit"))
+	}
+	rest = strings.TrimSpace(strings.TrimSuffix(rest, ";"))
+	if rest == "" {
+		return "", false, fmt.Errorf("EXPLAIN VISUAL needs a query")
+	}
+	return rest, commit, nil
+}
+
+// isSpace reports whether r is whitespace.
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// explainVisual runs EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) for the query inside a
+// transaction and renders the plan as a colored tree. The transaction is rolled
+// back unless commit is set, so analyzing an UPDATE does not change any data.
+// Committed changes ask first in dry-run mode or with a confirm_rows limit, like
+// any other data-modifying statement.
+func (e *Executor) explainVisual(query string, commit bool) (string, error) {
+	now := time.Now()
+	tx, err := e.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var raw []byte
+	e.finalSQL = "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) " + query
+	if err := tx.QueryRow(e.finalSQL).Scan(&raw); err != nil {
+		return "", err
+	}
+	var results []explainResult
+	if err := json.Unmarshal(raw, &results); err != nil {
+		return "", err
+	}
+	if len(results) == 0 || results[0].Plan == nil {
+		return "", fmt.Errorf("EXPLAIN returned no plan")
+	}
+	result := results[0]
+	computeExclusiveTime(result.Plan)
+	renderPlan(e.out(), result)
+
+	if commit {
+		statements, ok, err := transactionalDML(query)
+		if err != nil {
+			return "", err
+		}
+		if ok && !confirmUncounted(statements, time.Since(now).Seconds()) {
+			return "", fmt.Errorf("Rolled back, no rows were changed")
+		}
+		if err := tx.Commit(); err != nil {
+			return "", err
+		}
+		return "\nStatement committed", nil
+	}
+	return "\nStatement rolled back", nil
+}
+
+// computeExclusiveTime sets the time each node spent without its children. Actual
+// times are per loop, so they are multiplied by the number of loops first.
+func computeExclusiveTime(node *planNode) float64 {
+	total := node.ActualTotalTime * node.ActualLoops
+	children := 0.0
+	for _, child := range node.Plans {
+		children += computeExclusiveTime(child)
+	}
+	node.exclusive = total - children
+	if node.exclusive < 0 {
+		node.exclusive = 0
+	}
+	return total
+}
+
+// hottestNodes returns up to n nodes with the highest exclusive time.
+func hottestNodes(root *planNode, n int) map[*planNode]bool {
+	var all []*planNode
+	var collect func(node *planNode)
+	collect = func(node *planNode) {
+		all = append(all, node)
+		for _, child := range node.Plans {
+			collect(child)
+		}
+	}
+	collect(root)
+	sort.SliceStable(all, func(i, j int) bool { return all[i].exclusive > all[j].exclusive })
+	hot := map[*planNode]bool{}
+	for i := 0; i < n && i < len(all); i++ {
+		if all[i].exclusive > 0 {
+			hot[all[i]] = true
+		}
+	}
+	return hot
+}
+
+// renderPlan writes the plan tree with time shares, row estimates and buffers.
+func renderPlan(w io.Writer, result explainResult) {
+	fmt.Fprintf(w, "%sPlanning time: %.3f ms  Execution time: %.3f ms%s\n\n",
+		boldText, result.PlanningTime, result.ExecutionTime, resetText)
+	hot := hottestNodes(result.Plan, 3)
+	var walk func(node *planNode, prefix string, last bool, root bool)
+	walk = func(node *planNode, prefix string, last bool, root bool) {
+		branch, childPrefix := "", ""
+		if !root {
+			branch, childPrefix = "├─ ", prefix+"│  "
+			if last {
+				branch, childPrefix = "└─ ", prefix+"   "
+			}
+		}
+		share := 0.0
+		if result.ExecutionTime > 0 {
+			share = 100 * node.exclusive / result.ExecutionTime
+		}
+		color := dimText
+		switch {
+		case hot[node] && share >= 20:
+			color = redText + boldText
+		case hot[node] || share >= 5:
+			color = yellowText
+		}
+		fmt.Fprintf(w, "%s%s%s%s%s  %5.1f%%  %.3f ms%s\n",
+			prefix, branch, color, planNodeTitle(node), resetText, share, node.exclusive, hotMarker(hot[node]))
+
+		detail := childPrefix
+		if len(node.Plans) > 0 {
+			detail += "│  "
+		} else {
+			detail += "   "
+		}
+		if node.ActualLoops == 0 {
+			fmt.Fprintf(w, "%s%s(never executed)%s\n", detail, dimText, resetText)
+		} else {
+			fmt.Fprintf(w, "%srows: estimated %.0f, actual %.0f x %.0f loops%s\n",
+				detail, node.PlanRows, node.ActualRows, node.ActualLoops, misestimateWarning(node))
+		}
+		if node.SharedHitBlocks > 0 || node.SharedReadBlocks > 0 {
+			fmt.Fprintf(w, "%sbuffers: hit %d, read %d\n", detail, node.SharedHitBlocks, node.SharedReadBlocks)
+		}
+		for _, cond := range []struct{ label, value string }{
+			{"index cond", node.IndexCond}, {"hash cond", node.HashCond},
+			{"merge cond", node.MergeCond}, {"filter", node.Filter},
+		} {
+			if cond.value != "" {
+				fmt.Fprintf(w, "%s%s%s: %s%s\n", detail, cyanText, cond.label, cond.value, resetText)
+			}
+		}
+		if node.RowsRemovedByFilter > 0 {
+			fmt.Fprintf(w, "%srows removed by filter: %.0f\n", detail, node.RowsRemovedByFilter)
+		}
+		for i, child := range node.Plans {
+			walk(child, childPrefix, i == len(node.Plans)-1, false)
+		}
+	}
+	walk(result.Plan, "", true, true)
+}
+
+// planNodeTitle describes the node, e.g. "Index Scan using orders_pkey on orders o".
+func planNodeTitle(node *planNode) string {
+	title := node.NodeType
+	if node.Strategy != "" && node.NodeType == "Aggregate" {
+		title = node.Strategy + " " + title
+	}
+	if node.JoinType != "" && node.JoinType != "Inner" {
+		title += " " + node.JoinType
+	}
+	if node.IndexName != "" {
+		title += " using " + node.IndexName
+	}
+	if node.RelationName != "" {
+		title += " on " + node.RelationName
+		if node.Alias != "" && node.Alias != node.RelationName {
+			title += " " + node.Alias
+		}
+	}
+	return title
+}
+
+// hotMarker labels one of the nodes with the highest exclusive time.
+func hotMarker(hot bool) string {
+	if !hot {
+		return ""
+	}
+	return "  " + redText + "◀ hot" + resetText
+}
+
+// misestimateWarning flags nodes whose actual rows differ from the estimate by
+// at least misestimateFactor.
+func misestimateWarning(node *planNode) string {
+	estimated, actual := node.PlanRows, node.ActualRows
+	if estimated < 1 {
+		estimated = 1
+	}
+	if actual < 1 {
+		actual = 1
+	}
+	switch {
+	case actual/estimated >= misestimateFactor:
+		return fmt.Sprintf("  %s⚠ underestimated %.0fx%s", redText, actual/estimated, resetText)
+	case estimated/actual >= misestimateFactor:
+		return fmt.Sprintf("  %s⚠ overestimated %.0fx%s", yellowText, estimated/actual, resetText)
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package pgterm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// showFilter holds the optional LIKE and IN clauses accepted by the SHOW commands.
+type showFilter struct {
+	Like   string // LIKE pattern, "%" when not given
+	Schema string // Schema named by IN, folded like PostgreSQL does, empty when not given
+}
+
+// parseShowFilter parses the trailing [LIKE 'pattern'] [IN schema] clauses of a SHOW command.
+func parseShowFilter(args []string) (showFilter, error) {
+	filter := showFilter{Like: "%"}
+	for i := 0; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "LIKE":
+			if i+1 >= len(args) {
+				return filter, fmt.Errorf("LIKE needs a pattern")
+			}
+			// the pattern may contain spaces, so collect words up to the closing quote
+			end := i + 1
+			for end < len(args)-1 && !isClosedLiteral(strings.Join(args[i+1:end+1], " ")) {
+				end++
+			}
+			pattern, err := unquoteLiteral(strings.Join(args[i+1:end+1], " "))
+			if err != nil {
+				return filter, err
+			}
+			filter.Like = pattern
+			i = end
+		case "IN", "FROM":
+			if i+1 >= len(args) {
+				return filter, fmt.Errorf("%s needs a schema name", strings.ToUpper(args[i]))
+			}
+			// a quoted schema name may contain spaces
+			end := i + 1
+			for end < len(args)-1 && strings.Count(strings.Join(args[i+1:end+1], " "), `"`)%2 == 1 {
+				end++
+			}
+			schema, err := resolveSchema(strings.Join(args[i+1:end+1], " "))
+			if err != nil {
+				return filter, err
+			}
+			filter.Schema = schema
+			i = end
+		default:
+			return filter, fmt.Errorf("unexpected %s", args[i])
+		}
+	}
+	return filter, nil
+}
+
+// isClosedLiteral reports whether s is a complete single-quoted string literal.
+func isClosedLiteral(s string) bool {
+	if len(s) < 2 || !strings.HasPrefix(s, "'") || !strings.HasSuffix(s, "'") {
+		return false
+	}
+	// an even number of quotes inside means the last quote is not escaped
+	return strings.Count(s[1:len(s)-1], "'")%2 == 0
+}
+
+// unquoteLiteral strips the quotes of a single-quoted literal and unescapes doubled quotes.
+// Unquoted words are returned unchanged.
+func unquoteLiteral(s string) (string, error) {
+	if !strings.HasPrefix(s, "'") {
+		return s, nil
+	}
+	if !isClosedLiteral(s) {
+		return "", fmt.Errorf("unterminated string literal: %s", s)
+	}
+	return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+}
+
+// matchLike reports whether s matches the SQL LIKE pattern, ignoring case.
+// It is used for filtering rows that are assembled client-side.
+func matchLike(s, pattern string) bool {
+	var re strings.Builder
+	re.WriteString("(?is)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			re.WriteString(".*")
+		case r == '_':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("$")
+	matched, _ := regexp.MatchString(re.String(), s)
+	return matched
+}
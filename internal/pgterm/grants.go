@@ -0,0 +1,180 @@
+package pgterm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// showGrantsSQL lists every privilege held by a role, either directly or through
+// PUBLIC, together with its role memberships and the default privileges that
+// will apply to objects created in the future. ACLs are decoded with aclexplode
+// and a NULL ACL is replaced by the owner defaults from acldefault.
+const showGrantsSQL = `
+    WITH target AS (
+        SELECT oid FROM pg_roles WHERE rolname = $1
+    ), acl AS (
+        SELECT 'database' AS object_type, quote_ident(d.datname) AS object, a.*
+        FROM pg_database d,
+             aclexplode(COALESCE(d.datacl, acldefault('d', d.datdba))) a
+        UNION ALL
+        SELECT 'schema', quote_ident(n.nspname), a.*
+        FROM pg_namespace n,
+             aclexplode(COALESCE(n.nspacl, acldefault('n', n.nspowner))) a
+        WHERE n.nspname !~ '^pg_' AND n.nspname <> 'information_schema'
+        UNION ALL
+        SELECT CASE c.relkind WHEN 'S' THEN 'sequence' WHEN 'v' THEN 'view' WHEN 'm' THEN 'matview'
+                              WHEN 'f' THEN 'foreign table' ELSE 'table' END,
+               quote_ident(n.nspname) || '.' || quote_ident(c.relname), a.*
+        FROM pg_class c
+        JOIN pg_namespace n ON n.oid = c.relnamespace,
+             aclexplode(COALESCE(c.relacl, acldefault(CASE WHEN c.relkind = 'S' THEN 's' ELSE 'r' END::"char", c.relowner))) a
+        WHERE c.relkind IN ('r', 'p', 'v', 'm', 'S', 'f')
+          AND n.nspname !~ '^pg_' AND n.nspname <> 'information_schema'
+        UNION ALL
+        SELECT CASE p.prokind WHEN 'p' THEN 'procedure' ELSE 'function' END,
+               p.oid::regprocedure::text, a.*
+        FROM pg_proc p
+        JOIN pg_namespace n ON n.oid = p.pronamespace,
+             aclexplode(COALESCE(p.proacl, acldefault('f', p.proowner))) a
+        WHERE n.nspname !~ '^pg_' AND n.nspname <> 'information_schema'
+    )
+    SELECT object_type, object,
+           CASE WHEN grantee = 0 THEN 'PUBLIC' ELSE pg_get_userbyid(grantee) END AS grantee,
+           privilege_type AS privilege, is_grantable AS grantable,
+           pg_get_userbyid(grantor) AS grantor
+    FROM acl
+    WHERE grantee IN (SELECT oid FROM target UNION ALL SELECT 0)
+    UNION ALL
+    SELECT 'role', quote_ident(g.rolname), $1::text, 'MEMBER', m.admin_option, pg_get_userbyid(m.grantor)
+    FROM pg_auth_members m
+    JOIN pg_roles g ON g.oid = m.roleid
+    WHERE m.member = (SELECT oid FROM target)
+    UNION ALL
+    SELECT 'default ' || CASE d.defaclobjtype WHEN 'r' THEN 'tables' WHEN 'S' THEN 'sequences'
+                                              WHEN 'f' THEN 'functions' WHEN 'T' THEN 'types'
+                                              ELSE 'schemas' END,
+           COALESCE(quote_ident(n.nspname), '*') || ' created by ' || pg_get_userbyid(d.defaclrole),
+           $1::text, a.privilege_type, a.is_grantable, pg_get_userbyid(a.grantor)
+    FROM pg_default_acl d
+    LEFT JOIN pg_namespace n ON n.oid = d.defaclnamespace,
+         aclexplode(d.defaclacl) a
+    WHERE a.grantee = (SELECT oid FROM target)
+    ORDER BY 1, 2, 4;`
+
+// showRolesSQL lists roles with their attributes and memberships. Built-in pg_*
+// roles are only listed when a LIKE pattern is given.
+const showRolesSQL = `
+    SELECT r.rolname AS role,
+           r.rolsuper AS superuser,
+           r.rolinherit AS inherit,
+           r.rolcreaterole AS create_role,
+           r.rolcreatedb AS create_db,
+           r.rolcanlogin AS login,
+           r.rolreplication AS replication,
+           r.rolbypassrls AS bypass_rls,
+           CASE WHEN r.rolconnlimit < 0 THEN 'unlimited' ELSE r.rolconnlimit::text END AS conn_limit,
+           to_char(r.rolvaliduntil, 'YYYY-MM-DD HH24:MI:SS') AS valid_until,
+           COALESCE((SELECT string_agg(g.rolname, ', ' ORDER BY g.rolname)
+                     FROM pg_auth_members m
+                     JOIN pg_roles g ON g.oid = m.roleid
+                     WHERE m.member = r.oid), '') AS member_of
+    FROM pg_roles r
+    WHERE r.rolname ILIKE $1
+      AND ($1 <> '%' OR r.rolname !~ '^pg_')
+    ORDER BY r.rolname;`
+
+// showPrivilegesSQL decodes the table ACL and any column ACLs of a relation.
+const showPrivilegesSQL = `
+    SELECT CASE WHEN a.grantee = 0 THEN 'PUBLIC' ELSE pg_get_userbyid(a.grantee) END AS grantee,
+           a.privilege_type AS privilege,
+           a.is_grantable AS grantable,
+           pg_get_userbyid(a.grantor) AS grantor,
+           '' AS column_name
+    FROM pg_class c,
+         aclexplode(COALESCE(c.relacl, acldefault(CASE WHEN c.relkind = 'S' THEN 's' ELSE 'r' END::"char", c.relowner))) a
+    WHERE c.oid = $1::regclass
+    UNION ALL
+    SELECT CASE WHEN a.grantee = 0 THEN 'PUBLIC' ELSE pg_get_userbyid(a.grantee) END,
+           a.privilege_type, a.is_grantable, pg_get_userbyid(a.grantor), att.attname::text
+    FROM pg_attribute att,
+         aclexplode(att.attacl) a
+    WHERE att.attrelid = $1::regclass
+      AND att.attacl IS NOT NULL
+      AND NOT att.attisdropped
+    ORDER BY 1, 5, 2;`
+
+// showGrants renders the privileges of the role, or of the current user when role is empty.
+func (e *Executor) showGrants(role string) (string, error) {
+	if role == "" {
+		if err := e.DB.QueryRow("SELECT current_user;").Scan(&role); err != nil {
+			return "", err
+		}
+	}
+	var exists bool
+	if err := e.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1);", role).Scan(&exists); err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("role %s does not exist", role)
+	}
+	return e.renderQuery(showGrantsSQL, role)
+}
+
+// showRoles renders the roles matching the LIKE pattern.
+func (e *Executor) showRoles(filter showFilter) (string, error) {
+	return e.renderQuery(showRolesSQL, filter.Like)
+}
+
+// showPrivileges renders the privileges granted on a table. Unqualified names are
+// looked up in the active schema.
+func (e *Executor) showPrivileges(table string) (string, error) {
+	name, err := resolveTable(table)
+	if err != nil {
+		return "", err
+	}
+	return e.renderQuery(showPrivilegesSQL, name.String())
+}
+
+// parseGrantsCommand handles SHOW GRANTS [FOR role], SHOW ROLES [LIKE 'pattern']
+// and SHOW PRIVILEGES ON <table>. args starts after SHOW, cmd is the whole
+// command as typed, so quoted names with spaces survive.
+func (e *Executor) parseGrantsCommand(cmd string, args []string) (string, error) {
+	switch strings.ToUpper(args[0]) {
+	case "GRANTS":
+		if len(args) == 1 {
+			return e.showGrants("")
+		}
+		if len(args) < 3 || strings.ToUpper(args[1]) != "FOR" {
+			return "", fmt.Errorf("usage: SHOW GRANTS [FOR role]")
+		}
+		// a string literal is the exact role name, an identifier is folded to lower
+		// case unless it is quoted, like PostgreSQL does; either may contain spaces
+		name := strings.TrimSpace(strings.TrimSuffix(skipFields(cmd, 3), ";"))
+		if strings.HasPrefix(name, "'") {
+			role, err := unquoteLiteral(name)
+			if err != nil {
+				return "", err
+			}
+			return e.showGrants(role)
+		}
+		parts, err := parseName(name)
+		if err != nil {
+			return "", err
+		}
+		if len(parts) != 1 {
+			return "", fmt.Errorf("invalid role name: %s", name)
+		}
+		return e.showGrants(parts[0])
+	case "ROLES":
+		filter, err := parseShowFilter(args[1:])
+		if err != nil {
+			return "", err
+		}
+		return e.showRoles(filter)
+	default:
+		if len(args) < 3 || strings.ToUpper(args[1]) != "ON" {
+			return "", fmt.Errorf("usage: SHOW PRIVILEGES ON <table>")
+		}
+		return e.showPrivileges(skipFields(cmd, 3))
+	}
+}
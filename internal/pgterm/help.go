@@ -12,15 +12,106 @@ SHOW SCHEMAS;
 SHOW TABLES;
     → Lists all tables in the current schema (%s).
 
+SHOW FULL TABLES [LIKE 'pattern'] [IN schema];
+    → Lists tables, partitioned tables, views, materialized views and foreign
+      tables together with their type, and the parent table and bound of partitions.
+
+SHOW VIEWS | MATERIALIZED VIEWS | FUNCTIONS | PROCEDURES | TRIGGERS
+   | SEQUENCES | EXTENSIONS | TYPES | POLICIES [LIKE 'pattern'] [IN schema];
+    → Lists the objects of that kind in the current schema, e.g. function
+      signatures, sequence values, enum labels or row level security policies.
+
+SHOW TABLE STATUS [LIKE 'pattern'] [IN schema];
+    → Shows row estimates, table/index/TOAST sizes, dead tuples, last (auto)vacuum
+      and (auto)analyze times and scan counts for the tables of the schema.
+
+SHOW INDEX ADVICE [IN schema];
+    → Flags never-scanned, duplicate, prefix-redundant and invalid indexes and
+      foreign keys without a supporting index, with DDL to fix each finding.
+      The DDL is only printed, never executed. An index that is still being built
+      with CREATE INDEX CONCURRENTLY is invalid too; check SHOW PROGRESS first.
+
+SHOW BLOAT [FOR table];
+    → Estimates table and btree index bloat of the current schema from planner
+      statistics, sorted by wasted space. For a single table pgstattuple is used
+      for exact numbers when the extension is installed; a partitioned table is
+      measured partition by partition, which needs pgstattuple.
+
 SHOW DATABASES;
-    → Lists all available databases (excluding templates).
+    → Lists all available databases (excluding templates) with owner, encoding,
+      collation, size and connection limit.
+
+SHOW DATABASE SIZES;
+SHOW SCHEMA SIZES;
+SHOW TABLE SIZES [LIMIT n];
+    → Ranks databases, schemas or tables by size. Schema and table sizes break out
+      table, TOAST and index sizes.
 
 SHOW CREATE TABLE <table>;
     → Outputs a SQL CREATE TABLE statement for the specified table.
 
+SHOW [FULL] PROCESSLIST;
+    → Lists client connections with state, wait event, transaction and query age.
+      FULL shows the complete query text instead of a truncated one.
+
+SHOW VARIABLES [LIKE 'pattern'];
+    → Lists server settings with their unit, source and pending restart state.
+
+SHOW GLOBAL STATUS [LIKE 'pattern'];
+    → Summarises database, checkpoint, background writer and WAL statistics.
+
+SHOW <setting>;
+    → Shows the value of a single server setting, e.g. SHOW work_mem;
+
+SHOW GRANTS [FOR role];
+    → Lists the privileges of the role (default: current user) on databases, schemas,
+      tables, sequences and functions, its role memberships and default privileges.
+      Unquoted role names are folded to lower case; quote them to keep their case.
+
+SHOW ROLES [LIKE 'pattern'];
+    → Lists roles with their attributes, connection limit and memberships.
+
+SHOW PRIVILEGES ON <table>;
+    → Lists grantee, privilege, grantable and grantor for the table and its columns.
+
+SHOW PROGRESS;
+    → Lists running VACUUM, ANALYZE, CREATE INDEX, CLUSTER, base backup and COPY
+      operations with percent done, rate and ETA from two samples a second apart.
+
+SHOW REPLICATION;
+    → On a primary lists the standbys with write/flush/replay lag in bytes and time.
+      On a standby shows the WAL receiver status and replay delay.
+
+SHOW SLOTS;
+    → Lists replication slots with the WAL they retain and flags inactive slots
+      that are holding back WAL.
+
+SHOW LOCKS;
+    → Shows who blocks whom as a tree with the root blockers at the top, including
+      lock mode, relation, wait duration and the query of each backend.
+
+SHOW SLOW QUERIES [LIMIT n] [ORDER BY total|mean|calls|io];
+    → Lists the statements of this database from pg_stat_statements with calls,
+      total/mean/stddev time, rows and shared buffer hit ratio.
+
+RESET SLOW QUERIES;
+    → Clears the pg_stat_statements statistics. Asks for confirmation first.
+
+KILL [QUERY | CONNECTION] <pid>;
+    → Terminates the backend (pg_terminate_backend) or, with QUERY, cancels its
+      running query (pg_cancel_backend). Asks for confirmation first.
+
+LISTEN <channel>;
+UNLISTEN <channel> | *;
+    → Starts or stops listening for NOTIFY messages on a channel. Notifications are
+      printed above the prompt as they arrive, or after the running command finishes.
+      pgterm listen <channel...> prints them as JSON lines outside the REPL.
+
 DESCRIBE <table>;
 DESC <table>;
     → Shows column names, data types, and nullability for the specified table.
+      Names follow PostgreSQL rules: orders and ORDERS are the same table,
+      "OrderItems" keeps its case, and sales.orders looks in another schema.
 
 USE SCHEMA <schema>;
     → Sets the active schema (affects future queries).
@@ -28,15 +119,92 @@ USE SCHEMA <schema>;
 USE DATABASE <dbname>;
     → Connects to a different database (\\c <dbname> equivalent).
 
+EXPLAIN VISUAL [--commit] <query>;
+\explain [--commit] <query>
+    → Runs EXPLAIN (ANALYZE, BUFFERS) and draws the plan as a colored tree with the
+      share of time per node, estimated vs actual rows and buffer hits/reads.
+      The statement runs in a transaction that is rolled back unless --commit is given.
+
 CREATE ...;
 GRANT ...;
 ALTER ...;
     → These commands are passed directly to the database.
 
+\top [seconds]
+    → Opens a full-screen, auto-refreshing view of the server activity.
+      Keys: s sort, u/d/t filter by user/database/state, c cancel, k terminate, q quit.
+
+\progress <pid>
+    → Follows the operation of one backend with a live progress bar until it
+      finishes or Ctrl-C is pressed.
+
+\dryrun [on|off]
+    → Toggles dry-run mode (also pgterm connect --dry-run). Every INSERT, UPDATE,
+      DELETE and MERGE runs in a transaction, reports the affected rows and asks
+      whether to commit or roll back. With confirm_rows set in the config file,
+      statements affecting more rows always ask, even outside dry-run mode. Several
+      statements on one line run in a single transaction with a count for each; they
+      may not contain BEGIN, COMMIT or ROLLBACK.
+
+\notices [on|off]
+    → Shows or hides NOTICE and WARNING messages from the server, such as the
+      output of RAISE NOTICE. They are shown by default, before the result.
+
+\watch [seconds] [--until empty|change]
+    → Re-runs the previous statement every few seconds (default 2) and redraws the
+      result until Ctrl-C, or until the result is empty or differs from the first run.
+      Only statements that return rows, are allowed in a read-only session and ran
+      without error are watched, on a separate read-only connection: functions that
+      write, such as nextval(), fail, but functions that act on the server without
+      writing, such as pg_terminate_backend(), run on every refresh.
+
+\h
+    → Shows this help.
+
+Read-only sessions:
+    pgterm connect --read-only (or "read_only": true in a profile) opens the session
+    with default_transaction_read_only = on and refuses write statements before they
+    are sent, including changes to the read-only settings with SET or set_config,
+    KILL, RESET SLOW QUERIES and cancelling or terminating backends in \top.
+    The prompt shows [RO]. pgterm top --read-only, or top with a read-only profile,
+    refuses cancelling and terminating backends as well.
+
+Environments:
+    A connection is labelled dev, staging or prod with --env, the "environment" of
+    its profile, or the environment_hosts patterns of the config file. The label is
+    shown in the prompt (green, yellow, red) and in application_name. Production
+    sessions start with a red banner and ask before running any DDL.
+
+Audit log:
+    With "audit": {"enabled": true} in the config file or a profile, every statement
+    is appended to a JSON-lines file with the time, OS user, connection, database,
+    schema, final SQL, duration, rows and error. Passwords are always redacted, other
+    string literals with "redact": true, in the SQL and in the error text.
+
+Protected objects:
+    Schemas and tables listed under "protected" in the config file (glob patterns
+    such as billing, billing.* or public.payments) cannot be dropped, truncated,
+    altered with DROP or emptied by a DELETE without WHERE, unless pgterm connect
+    is started with --break-glass. Unqualified names are resolved by the server,
+    the same way the statement resolves them.
+
+Destructive statement guard:
+    Before running SQL pgterm checks it against these rules (the action in brackets
+    is the default):
+      delete-without-where  DELETE without a WHERE clause, or WHERE true [confirm]
+      update-without-where  UPDATE without a WHERE clause, or WHERE true [confirm]
+      truncate              TRUNCATE [confirm]
+      drop-table            DROP TABLE, FOREIGN TABLE or MATERIALIZED VIEW [confirm, type the name]
+      drop-schema           DROP SCHEMA [confirm, type the name]
+      drop-database         DROP DATABASE [confirm, type the name]
+      drop-column           ALTER TABLE ... DROP COLUMN [confirm]
+    Statements inside WITH and EXPLAIN ANALYZE are checked too. Each rule can be set
+    to allow, confirm or deny in the "guard" section of the config file.
+
 All other valid SQL statements (SELECT, INSERT, UPDATE, DELETE, etc.) are supported and passed directly to PostgreSQL.
 
 Note:
-    - Semicolons (;) are mandatory.
+    - Semicolons (;) are mandatory, except for backslash commands.
     - Commands are case-insensitive.
     - Current schema: %s
     - Current database: %s
@@ -0,0 +1,291 @@
+package pgterm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/lib/pq"
+	"golang.org/x/term"
+)
+
+// Reconnect intervals of the LISTEN connection after it is lost.
+const (
+	listenMinReconnect = time.Second
+	listenMaxReconnect = time.Minute
+)
+
+// Notification is a NOTIFY message as written by TailNotifications.
+type Notification struct {
+	Time    time.Time `json:"time"`
+	Channel string    `json:"channel"`
+	Payload string    `json:"payload"`
+	PID     int       `json:"pid"` // backend process that sent the NOTIFY
+}
+
+// listenConnection holds the settings of the REPL connection, used to open the
+// dedicated LISTEN connection. LISTEN is not available when it is nil.
+var listenConnection *Connection
+
+// listener receives the notifications of the REPL, nil until the first LISTEN.
+var listener *pq.Listener
+
+// Display state of the notifications in the REPL. While a command runs they are
+// queued in pendingNotices and printed when it finishes; while the prompt waits
+// for input they are printed above the input line, which is drawn again with the
+// text before and after the cursor as last drawn by the prompt. displayMu also
+// serialises the output of the prompt, see promptWriter.
+var (
+	displayMu      sync.Mutex
+	executing      bool
+	pendingNotices []string
+	typedBefore    string // input as last seen by the completer
+	typedAfter     string
+	inputBefore    string // input as last drawn on the terminal
+	inputAfter     string
+)
+
+// openListener starts a LISTEN connection and waits for its first connection
+// attempt, so bad settings fail instead of being retried forever. Later
+// disconnects and reconnects are passed to report.
+func openListener(conn *Connection, report func(event pq.ListenerEventType, err error)) (*pq.Listener, error) {
+	var once sync.Once
+	first := make(chan error, 1)
+	l := pq.NewListener(conn.dsn(), listenMinReconnect, listenMaxReconnect, func(event pq.ListenerEventType, err error) {
+		isFirst := false
+		once.Do(func() {
+			isFirst = true
+			first <- err
+		})
+		if !isFirst && report != nil {
+			report(event, err)
+		}
+	})
+	if err := <-first; err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// TailNotifications listens on the channels and writes every notification to w
+// as a JSON line until interrupted with Ctrl-C. Channel names are used as given,
+// without folding them to lower case.
+func TailNotifications(conn *Connection, channels []string, w io.Writer) error {
+	l, err := openListener(conn, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected, pq.ListenerEventConnectionAttemptFailed:
+			fmt.Fprintf(os.Stderr, "listen: connection lost: %s\n", err)
+		case pq.ListenerEventReconnected:
+			fmt.Fprintln(os.Stderr, "listen: reconnected, notifications sent in between are lost")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	for _, channel := range channels {
+		if err := l.Listen(channel); err != nil {
+			return fmt.Errorf("LISTEN %s: %s", channel, err)
+		}
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	encoder := json.NewEncoder(w)
+	ping := time.NewTicker(time.Minute)
+	defer ping.Stop()
+	for {
+		select {
+		case n := <-l.Notify:
+			// nil is sent after a reconnect, reported by the event callback
+			if n == nil {
+				continue
+			}
+			err := encoder.Encode(Notification{
+				Time:    time.Now(),
+				Channel: n.Channel,
+				Payload: n.Extra,
+				PID:     n.BePid,
+			})
+			if err != nil {
+				return err
+			}
+		case <-ping.C:
+			// a dead connection is only noticed when something is sent on it
+			go l.Ping()
+		case <-interrupt:
+			return nil
+		}
+	}
+}
+
+// listen runs LISTEN channel and UNLISTEN channel or * in the REPL. The channels
+// are listened on by a dedicated connection, opened by the first LISTEN, whose
+// notifications are printed as they arrive.
+func (e *Executor) listen(cmd string, unlisten bool) (string, error) {
+	verb := "LISTEN"
+	if unlisten {
+		verb = "UNLISTEN"
+	}
+	arg := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(skipFields(cmd, 1)), ";"))
+	if arg == "" {
+		return "", fmt.Errorf("%s needs a channel name", verb)
+	}
+	if unlisten && arg == "*" {
+		if listener == nil {
+			return "UNLISTEN\n", nil
+		}
+		return "UNLISTEN\n", listener.UnlistenAll()
+	}
+	parts, err := parseName(arg)
+	if err != nil {
+		return "", err
+	}
+	if len(parts) != 1 {
+		return "", fmt.Errorf("invalid channel name: %s", arg)
+	}
+	channel := parts[0]
+
+	if unlisten {
+		if listener == nil {
+			return "UNLISTEN\n", nil
+		}
+		if err := listener.Unlisten(channel); err != nil && err != pq.ErrChannelNotOpen {
+			return "", err
+		}
+		return "UNLISTEN\n", nil
+	}
+	if listener == nil {
+		if listenConnection == nil {
+			return "", fmt.Errorf("LISTEN is not available on this connection")
+		}
+		l, err := openListener(listenConnection, reportListener)
+		if err != nil {
+			return "", fmt.Errorf("could not open the LISTEN connection: %s", err)
+		}
+		listener = l
+		go deliverNotifications(l)
+	}
+	if err := listener.Listen(channel); err != nil && err != pq.ErrChannelAlreadyOpen {
+		return "", err
+	}
+	return "LISTEN\n", nil
+}
+
+// deliverNotifications prints the notifications of the REPL listener until it is closed.
+func deliverNotifications(l *pq.Listener) {
+	for n := range l.Notify {
+		if n == nil {
+			continue
+		}
+		showAsync(fmt.Sprintf("%sAsynchronous notification \"%s\" with payload \"%s\" received from server process with PID %d.%s",
+			cyanText, n.Channel, n.Extra, n.BePid, resetText))
+	}
+}
+
+// reportListener shows lost and restored LISTEN connections in the REPL.
+func reportListener(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		showAsync(fmt.Sprintf("%sLISTEN connection lost, reconnecting: %s%s", yellowText, err, resetText))
+	case pq.ListenerEventReconnected:
+		showAsync(yellowText + "LISTEN connection restored, notifications sent in between are lost." + resetText)
+	}
+}
+
+// showAsync prints a message that arrives in the background, without mixing it
+// into the output of a running command or into the line being typed.
+func showAsync(msg string) {
+	displayMu.Lock()
+	defer displayMu.Unlock()
+	if executing {
+		pendingNotices = append(pendingNotices, msg)
+		return
+	}
+	os.Stdout.WriteString(redrawAbove(msg))
+}
+
+// redrawAbove returns the terminal output that replaces the input line with msg
+// and draws the prompt and the typed text again below it, with the cursor where
+// it was. The terminal is in raw mode, so lines end with \r\n.
+func redrawAbove(msg string) string {
+	prefix, _ := livePrefix()
+
+	var b strings.Builder
+	// move to the first line of the input when it wraps, then clear everything
+	// below, including the completion menu
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		if rows := (utf8.RuneCountInString(prefix) + utf8.RuneCountInString(inputBefore)) / width; rows > 0 {
+			fmt.Fprintf(&b, "\x1b[%dA", rows)
+		}
+	}
+	b.WriteString("\r\x1b[J")
+	b.WriteString(strings.ReplaceAll(msg, "\n", "\r\n") + "\r\n")
+	b.WriteString(prefixColor(session.GetEnvironment()) + prefix + resetText + inputBefore)
+	// save the cursor, draw the rest of the input and go back
+	b.WriteString("\x1b7" + inputAfter + "\x1b8")
+	return b.String()
+}
+
+// prefixColor is the ANSI code of the prompt color of environmentColor.
+func prefixColor(env string) string {
+	switch environmentColor(env) {
+	case prompt.Red:
+		return redText
+	case prompt.Yellow:
+		return yellowText
+	}
+	return greenText
+}
+
+// trackInput records the line being typed. The completer sees it just before the
+// prompt draws it, and promptWriter makes it the input redrawAbove draws.
+func trackInput(d prompt.Document) {
+	displayMu.Lock()
+	defer displayMu.Unlock()
+	typedBefore = d.TextBeforeCursor()
+	typedAfter = d.TextAfterCursor()
+}
+
+// promptWriter is the console writer of the prompt. The prompt buffers its output
+// and writes it to the terminal in Flush, which takes displayMu, so showAsync never
+// writes in the middle of a redraw and knows what the input line looks like.
+type promptWriter struct {
+	prompt.ConsoleWriter
+}
+
+func (w promptWriter) Flush() error {
+	displayMu.Lock()
+	defer displayMu.Unlock()
+	inputBefore, inputAfter = typedBefore, typedAfter
+	return w.ConsoleWriter.Flush()
+}
+
+// startExecuting holds back background messages while a command runs.
+func startExecuting() {
+	displayMu.Lock()
+	defer displayMu.Unlock()
+	executing = true
+	typedBefore, typedAfter = "", ""
+	inputBefore, inputAfter = "", ""
+}
+
+// stopExecuting prints the messages that arrived while a command ran.
+func stopExecuting() {
+	displayMu.Lock()
+	defer displayMu.Unlock()
+	executing = false
+	for _, msg := range pendingNotices {
+		fmt.Println(msg)
+	}
+	pendingNotices = nil
+}
@@ -0,0 +1,174 @@
+package pgterm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// lockParticipantsSQL returns every backend that is waiting on a lock or holding
+// a lock someone else waits for. Waiters report the lock they requested, blockers
+// the granted locks on relations or transactions that others are waiting for.
+// The %s placeholder is the expression for the time a waiter started waiting.
+const lockParticipantsSQL = `
+    WITH waiting AS (
+        SELECT * FROM pg_locks WHERE NOT granted
+    )
+    SELECT a.pid,
+           pg_blocking_pids(a.pid) AS blocked_by,
+           COALESCE(a.usename, '') AS user,
+           COALESCE(a.datname, '') AS database,
+           COALESCE(a.state, '') AS state,
+           COALESCE(w.mode, (
+               SELECT string_agg(DISTINCT l.mode, ', ')
+               FROM pg_locks l
+               WHERE l.pid = a.pid AND l.granted
+                 AND (l.relation IN (SELECT relation FROM waiting)
+                      OR l.transactionid IN (SELECT transactionid FROM waiting))), '') AS mode,
+           COALESCE(w.relation::regclass::text, (
+               SELECT string_agg(DISTINCT l.relation::regclass::text, ', ')
+               FROM pg_locks l
+               WHERE l.pid = a.pid AND l.granted
+                 AND l.relation IN (SELECT relation FROM waiting)), w.locktype, '') AS relation,
+           CASE WHEN w.pid IS NULL THEN ''
+                ELSE date_trunc('second', now() - %s)::text END AS waiting,
+           regexp_replace(COALESCE(a.query, ''), '\s+', ' ', 'g') AS query
+    FROM pg_stat_activity a
+    LEFT JOIN LATERAL (
+        SELECT * FROM waiting l WHERE l.pid = a.pid LIMIT 1
+    ) w ON true
+    WHERE a.pid IN (
+        SELECT pid FROM pg_stat_activity WHERE cardinality(pg_blocking_pids(pid)) > 0
+        UNION
+        SELECT unnest(pg_blocking_pids(pid)) FROM pg_stat_activity
+    );`
+
+// lockParticipant is a backend taking part in a blocking chain.
+type lockParticipant struct {
+	PID       int64
+	BlockedBy []int64
+	Columns   []string // user, database, state, mode, relation, waiting, query
+}
+
+// showLocks renders the blocking chains between backends as an indented tree
+// with the root blockers at the top level.
+func (e *Executor) showLocks() (string, error) {
+	now := time.Now()
+	version, err := e.serverVersionNum()
+	if err != nil {
+		return "", err
+	}
+	// pg_locks.waitstart is available from PostgreSQL 14
+	waitStart := "a.query_start"
+	if version >= 140000 {
+		waitStart = "COALESCE(w.waitstart, a.query_start)"
+	}
+	rows, err := e.DB.Query(fmt.Sprintf(lockParticipantsSQL, waitStart))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	participants := map[int64]*lockParticipant{}
+	for rows.Next() {
+		p := &lockParticipant{}
+		var user, database, state, mode, relation, waiting, query string
+		if err := rows.Scan(&p.PID, pq.Array(&p.BlockedBy), &user, &database, &state, &mode, &relation, &waiting, &query); err != nil {
+			return "", err
+		}
+		if runes := []rune(query); len(runes) > processListQueryLength {
+			query = string(runes[:processListQueryLength]) + "..."
+		}
+		p.Columns = []string{user, database, state, mode, relation, waiting, query}
+		participants[p.PID] = p
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if len(participants) == 0 {
+		return "No backend is waiting on a lock", nil
+	}
+
+	table := newTable(e.out())
+	table.Header([]string{"pid", "user", "database", "state", "mode", "relation", "waiting", "query"})
+	rowCount := 0
+	for _, line := range lockTree(participants) {
+		table.Append(append([]string{line.Label}, participants[line.PID].Columns...))
+		rowCount++
+	}
+	table.Render()
+	return e.rowsReturned(rowCount, time.Since(now).Seconds()), nil
+}
+
+// lockTreeLine is one row of the rendered blocking tree.
+type lockTreeLine struct {
+	PID   int64
+	Label string
+}
+
+// lockTree orders the participants depth first from their root blockers. A root
+// is a backend that is not blocked by any other participant. Waiters blocked by
+// several backends appear under each of them; cycles (deadlocks about to be
+// detected) are broken so that every backend is listed at least once.
+func lockTree(participants map[int64]*lockParticipant) []lockTreeLine {
+	children := map[int64][]int64{}
+	var roots []int64
+	for pid, p := range participants {
+		blocked := false
+		for _, blocker := range p.BlockedBy {
+			if _, ok := participants[blocker]; ok {
+				children[blocker] = append(children[blocker], pid)
+				blocked = true
+			}
+		}
+		if !blocked {
+			roots = append(roots, pid)
+		}
+	}
+	sortPids := func(pids []int64) {
+		sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+	}
+	for _, c := range children {
+		sortPids(c)
+	}
+	sortPids(roots)
+
+	var lines []lockTreeLine
+	listed := map[int64]bool{}
+	var walk func(pid int64, depth int, path map[int64]bool)
+	walk = func(pid int64, depth int, path map[int64]bool) {
+		label := fmt.Sprintf("%d", pid)
+		if depth > 0 {
+			label = strings.Repeat("   ", depth-1) + "└─ " + label
+		}
+		if path[pid] {
+			lines = append(lines, lockTreeLine{PID: pid, Label: label + " (cycle)"})
+			return
+		}
+		lines = append(lines, lockTreeLine{PID: pid, Label: label})
+		listed[pid] = true
+		path[pid] = true
+		for _, child := range children[pid] {
+			walk(child, depth+1, path)
+		}
+		delete(path, pid)
+	}
+	for _, root := range roots {
+		walk(root, 0, map[int64]bool{})
+	}
+	// backends that only block each other have no root; start from the lowest pid
+	var remaining []int64
+	for pid := range participants {
+		remaining = append(remaining, pid)
+	}
+	sortPids(remaining)
+	for _, pid := range remaining {
+		if !listed[pid] {
+			walk(pid, 0, map[int64]bool{})
+		}
+	}
+	return lines
+}
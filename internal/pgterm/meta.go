@@ -0,0 +1,139 @@
+package pgterm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isMetaCommand reports whether the input is a backslash command. Backslash
+// commands are handled by pgterm itself and do not need a terminating semicolon.
+func isMetaCommand(input string) bool {
+	return strings.HasPrefix(input, `\`)
+}
+
+// metaCommand runs a backslash command such as \top.
+func (p *Prompt) metaCommand(input string) {
+	args := strings.Fields(strings.TrimRight(input, "; \t\n"))
+	switch args[0] {
+	case `\h`, `\?`:
+		fmt.Println(helpString())
+	case `\top`:
+		interval := 2 * time.Second
+		if len(args) > 1 {
+			seconds, err := strconv.ParseFloat(args[1], 64)
+			if err != nil || seconds <= 0 {
+				fmt.Println(`usage: \top [seconds]`)
+				return
+			}
+			interval = time.Duration(seconds * float64(time.Second))
+		}
+		top := Top{DB: p.DB, Interval: interval, ReadOnly: session.GetReadOnly()}
+		if err := top.Run(); err != nil {
+			fmt.Println(err.Error())
+		}
+	case `\explain`:
+		if len(args) < 2 {
+			fmt.Println(`usage: \explain [--commit] <query>`)
+			return
+		}
+		executor := Executor{DB: p.DB}
+		statement := "EXPLAIN VISUAL " + strings.TrimSpace(strings.TrimPrefix(input, `\explain`))
+		resp, _, err := executor.Execute(statement)
+		if err != nil {
+			fmt.Println(formatError(err, statement, executor.finalSQL))
+			return
+		}
+		fmt.Println(resp)
+	case `\progress`:
+		if len(args) != 2 {
+			fmt.Println(`usage: \progress <pid>`)
+			return
+		}
+		pid, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("invalid process id: %s\n", args[1])
+			return
+		}
+		executor := Executor{DB: p.DB}
+		if err := executor.followProgress(pid); err != nil {
+			fmt.Println(err.Error())
+		}
+	case `\dryrun`:
+		dryRun := !session.GetDryRun()
+		if len(args) > 1 {
+			switch strings.ToLower(args[1]) {
+			case "on":
+				dryRun = true
+			case "off":
+				dryRun = false
+			default:
+				fmt.Println(`usage: \dryrun [on|off]`)
+				return
+			}
+		}
+		session.SetDryRun(dryRun)
+		if dryRun {
+			fmt.Println("Dry run is on: data changes ask before they are committed")
+		} else {
+			fmt.Println("Dry run is off")
+		}
+	case `\notices`:
+		notices := !session.GetNotices()
+		if len(args) > 1 {
+			switch strings.ToLower(args[1]) {
+			case "on":
+				notices = true
+			case "off":
+				notices = false
+			default:
+				fmt.Println(`usage: \notices [on|off]`)
+				return
+			}
+		}
+		session.SetNotices(notices)
+		if notices {
+			fmt.Println("Server notices are shown")
+		} else {
+			fmt.Println("Server notices are hidden")
+		}
+	case `\watch`:
+		if lastStatement == "" {
+			fmt.Println("There is no previous statement to watch")
+			return
+		}
+		interval, until, err := parseWatch(args[1:])
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		if err := watchable(lastStatement); err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		if p.Connection == nil {
+			fmt.Println("\\watch is not available on this connection")
+			return
+		}
+		// every run is a read-only transaction, so functions that write are refused
+		// by the server, e.g. nextval()
+		db, err := InitiateConnection(p.Connection.readOnly())
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		defer db.Close()
+		watch := Watch{
+			Executor:  &Executor{DB: db},
+			Statement: lastStatement,
+			Interval:  interval,
+			Until:     until,
+		}
+		if err := watch.Run(); err != nil {
+			fmt.Println(err.Error())
+		}
+	default:
+		fmt.Printf("unsupported command: %s\n", args[0])
+	}
+}
@@ -0,0 +1,34 @@
+package pgterm
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// noticeMu keeps notices of concurrent connections from interleaving.
+var noticeMu sync.Mutex
+
+// printNotice prints a NOTICE, WARNING, INFO or similar message from the server,
+// e.g. the output of RAISE NOTICE. It is called by the driver while the statement
+// runs, so the notices appear before the result.
+func printNotice(notice *pq.Error) {
+	if !session.GetNotices() {
+		return
+	}
+	color := cyanText
+	if notice.Severity == "WARNING" {
+		color = yellowText
+	}
+	noticeMu.Lock()
+	defer noticeMu.Unlock()
+	fmt.Fprintf(os.Stdout, "%s%s:  %s%s\n", color, notice.Severity, notice.Message, resetText)
+	if notice.Detail != "" {
+		fmt.Fprintf(os.Stdout, "%sDETAIL:  %s%s\n", color, notice.Detail, resetText)
+	}
+	if notice.Hint != "" {
+		fmt.Fprintf(os.Stdout, "%sHINT:  %s%s\n", color, notice.Hint, resetText)
+	}
+}
@@ -0,0 +1,154 @@
+package pgterm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// objectListings maps the object kinds of SHOW <kind> [LIKE 'pattern'] [IN schema]
+// to their catalog query. Every query takes the schema as $1 and the LIKE pattern as $2.
+var objectListings = map[string]string{
+	"FULL TABLES": `
+    SELECT c.relname AS name,
+           CASE c.relkind WHEN 'r' THEN 'BASE TABLE' WHEN 'p' THEN 'PARTITIONED TABLE'
+                          WHEN 'v' THEN 'VIEW' WHEN 'm' THEN 'MATERIALIZED VIEW'
+                          WHEN 'f' THEN 'FOREIGN TABLE' END AS table_type,
+           COALESCE(i.inhparent::regclass::text, '') AS partition_of,
+           CASE WHEN c.relispartition THEN pg_get_expr(c.relpartbound, c.oid) ELSE '' END AS partition_bound
+    FROM pg_class c
+    JOIN pg_namespace n ON n.oid = c.relnamespace
+    LEFT JOIN pg_inherits i ON i.inhrelid = c.oid AND c.relispartition
+    WHERE n.nspname = $1
+      AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+      AND c.relname ILIKE $2
+    ORDER BY c.relname;`,
+	"VIEWS": `
+    SELECT viewname AS name, viewowner AS owner
+    FROM pg_views
+    WHERE schemaname = $1 AND viewname ILIKE $2
+    ORDER BY viewname;`,
+	"MATERIALIZED VIEWS": `
+    SELECT matviewname AS name, matviewowner AS owner, ispopulated AS populated,
+           pg_size_pretty(pg_total_relation_size(format('%I.%I', schemaname, matviewname)::regclass)) AS size
+    FROM pg_matviews
+    WHERE schemaname = $1 AND matviewname ILIKE $2
+    ORDER BY matviewname;`,
+	"FUNCTIONS": `
+    SELECT p.proname AS name,
+           pg_get_function_arguments(p.oid) AS arguments,
+           COALESCE(pg_get_function_result(p.oid), '') AS returns,
+           CASE p.prokind WHEN 'f' THEN 'function' WHEN 'p' THEN 'procedure'
+                          WHEN 'a' THEN 'aggregate' WHEN 'w' THEN 'window' END AS kind,
+           l.lanname AS language,
+           CASE p.provolatile WHEN 'i' THEN 'immutable' WHEN 's' THEN 'stable' ELSE 'volatile' END AS volatility,
+           p.prosecdef AS security_definer
+    FROM pg_proc p
+    JOIN pg_namespace n ON n.oid = p.pronamespace
+    JOIN pg_language l ON l.oid = p.prolang
+    WHERE n.nspname = $1 AND p.proname ILIKE $2
+    ORDER BY p.proname, arguments;`,
+	"PROCEDURES": `
+    SELECT p.proname AS name,
+           pg_get_function_arguments(p.oid) AS arguments,
+           l.lanname AS language,
+           p.prosecdef AS security_definer
+    FROM pg_proc p
+    JOIN pg_namespace n ON n.oid = p.pronamespace
+    JOIN pg_language l ON l.oid = p.prolang
+    WHERE n.nspname = $1 AND p.prokind = 'p' AND p.proname ILIKE $2
+    ORDER BY p.proname, arguments;`,
+	"TRIGGERS": `
+    SELECT t.tgname AS name,
+           c.relname AS table_name,
+           CASE t.tgenabled WHEN 'D' THEN 'disabled' WHEN 'R' THEN 'replica'
+                            WHEN 'A' THEN 'always' ELSE 'enabled' END AS enabled,
+           pg_get_triggerdef(t.oid) AS definition
+    FROM pg_trigger t
+    JOIN pg_class c ON c.oid = t.tgrelid
+    JOIN pg_namespace n ON n.oid = c.relnamespace
+    WHERE NOT t.tgisinternal AND n.nspname = $1 AND t.tgname ILIKE $2
+    ORDER BY c.relname, t.tgname;`,
+	"SEQUENCES": `
+    SELECT sequencename AS name, data_type::text AS data_type, start_value, increment_by,
+           max_value, last_value AS current_value, cycle
+    FROM pg_sequences
+    WHERE schemaname = $1 AND sequencename ILIKE $2
+    ORDER BY sequencename;`,
+	"EXTENSIONS": `
+    SELECT e.extname AS name, e.extversion AS version, n.nspname AS schema,
+           COALESCE(a.default_version, '') AS default_version,
+           COALESCE(obj_description(e.oid, 'pg_extension'), '') AS description
+    FROM pg_extension e
+    JOIN pg_namespace n ON n.oid = e.extnamespace
+    LEFT JOIN pg_available_extensions a ON a.name = e.extname
+    WHERE ($1 = '' OR n.nspname = $1) AND e.extname ILIKE $2
+    ORDER BY e.extname;`,
+	"TYPES": `
+    SELECT t.typname AS name,
+           CASE t.typtype WHEN 'e' THEN 'enum' WHEN 'c' THEN 'composite' END AS kind,
+           CASE t.typtype
+               WHEN 'e' THEN (SELECT string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder)
+                              FROM pg_enum e WHERE e.enumtypid = t.oid)
+               ELSE (SELECT string_agg(quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod), ', ' ORDER BY a.attnum)
+                     FROM pg_attribute a WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped)
+           END AS definition
+    FROM pg_type t
+    JOIN pg_namespace n ON n.oid = t.typnamespace
+    LEFT JOIN pg_class c ON c.oid = t.typrelid
+    WHERE n.nspname = $1
+      AND (t.typtype = 'e' OR (t.typtype = 'c' AND c.relkind = 'c'))
+      AND t.typname ILIKE $2
+    ORDER BY t.typname;`,
+	"POLICIES": `
+    SELECT p.policyname AS name, p.tablename AS table_name,
+           c.relrowsecurity AS rls_enabled, c.relforcerowsecurity AS rls_forced,
+           p.permissive, array_to_string(p.roles, ', ') AS roles, p.cmd AS command,
+           COALESCE(p.qual, '') AS using_expression,
+           COALESCE(p.with_check, '') AS with_check
+    FROM pg_policies p
+    JOIN pg_namespace n ON n.nspname = p.schemaname
+    JOIN pg_class c ON c.relnamespace = n.oid AND c.relname = p.tablename
+    WHERE p.schemaname = $1 AND p.policyname ILIKE $2
+    ORDER BY p.tablename, p.policyname;`,
+}
+
+// objectKind returns the objectListings key named by the words after SHOW and
+// the number of words it used. MATVIEWS is an alias for MATERIALIZED VIEWS.
+func objectKind(args []string) (string, int) {
+	if len(args) == 0 {
+		return "", 0
+	}
+	first := strings.ToUpper(args[0])
+	if (first == "FULL" || first == "MATERIALIZED") && len(args) > 1 {
+		kind := first + " " + strings.ToUpper(args[1])
+		if _, ok := objectListings[kind]; ok {
+			return kind, 2
+		}
+		return "", 0
+	}
+	if first == "MATVIEWS" {
+		return "MATERIALIZED VIEWS", 1
+	}
+	if _, ok := objectListings[first]; ok {
+		return first, 1
+	}
+	return "", 0
+}
+
+// showObjects renders the listing of an object kind for the schema named by IN,
+// or the active schema. Extensions are listed for all schemas unless IN is given.
+func (e *Executor) showObjects(kind string, args []string) (string, error) {
+	query, ok := objectListings[kind]
+	if !ok {
+		return "", fmt.Errorf("unsupported object kind: %s", kind)
+	}
+	filter, err := parseShowFilter(args)
+	if err != nil {
+		return "", err
+	}
+	schema := filter.Schema
+	if schema == "" && kind != "EXTENSIONS" {
+		schema = session.GetSchema()
+	}
+	return e.renderQuery(query, schema, filter.Like)
+}
@@ -0,0 +1,141 @@
+package pgterm
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/renderer"
+	"github.com/olekukonko/tablewriter/tw"
+)
+
+// Terminal control sequences used by the full-screen views and colored output.
+const (
+	enterAltScreen = "\x1b[?1049h"
+	leaveAltScreen = "\x1b[?1049l"
+	clearScreen    = "\x1b[H\x1b[2J"
+	hideCursor     = "\x1b[?25l"
+	showCursor     = "\x1b[?25h"
+	reverseVideo   = "\x1b[7m"
+	boldText       = "\x1b[1m"
+	dimText        = "\x1b[2m"
+	redText        = "\x1b[31m"
+	greenText      = "\x1b[32m"
+	yellowText     = "\x1b[33m"
+	cyanText       = "\x1b[36m"
+	whiteOnRed     = "\x1b[97;41m"
+	resetText      = "\x1b[0m"
+)
+
+// newTable prepares a table writer that renders output as markdown.
+func newTable(w io.Writer) *tablewriter.Table {
+	return tablewriter.NewTable(w, tablewriter.WithRenderer(renderer.NewMarkdown(
+		tw.Rendition{
+			Settings: tw.Settings{Separators: tw.Separators{BetweenRows: tw.On}},
+			Borders:  tw.Border{Top: tw.On, Bottom: tw.On},
+		},
+	)), tablewriter.WithConfig(tablewriter.Config{
+		Row: tw.CellConfig{
+			Alignment: tw.CellAlignment{Global: tw.AlignLeft}, // Left-align row data
+		},
+		Header: tw.CellConfig{
+			Formatting: tw.CellFormatting{AutoFormat: tw.Off},
+			Alignment:  tw.CellAlignment{Global: tw.AlignLeft},
+		},
+		Footer: tw.CellConfig{
+			Alignment: tw.CellAlignment{Global: tw.AlignRight},
+		},
+	}))
+}
+
+// renderRows reads every row of the result set and renders it as a table on w.
+// It returns the number of rows rendered.
+func renderRows(w io.Writer, rows *sql.Rows) (int, error) {
+	return renderRowsLimit(w, rows, 0)
+}
+
+// renderRowsLimit reads every row of the result set but renders only the first
+// limit rows, all of them when limit is 0. It returns the number of rows read.
+func renderRowsLimit(w io.Writer, rows *sql.Rows, limit int) (int, error) {
+	columns, _ := rows.Columns()
+	table := newTable(w)
+
+	// Setup containers for scanning row values.
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	table.Header(columns)
+	rowCount := 0
+	// Read and format each row
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return rowCount, err
+		}
+		if limit > 0 && rowCount >= limit {
+			rowCount++
+			continue
+		}
+		row := make([]string, len(columns))
+		for i, val := range values {
+			row[i] = formatValue(val)
+		}
+		rowCount++
+		table.Append(row)
+	}
+	if err := rows.Err(); err != nil {
+		return rowCount, err
+	}
+	table.Render()
+	return rowCount, nil
+}
+
+// formatValue converts a scanned column value into its display form.
+func formatValue(val interface{}) string {
+	if val == nil {
+		return "NULL"
+	}
+	switch v := val.(type) {
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// renderQuery runs a query with bound arguments and renders the result as a table.
+// The returned string is the summary line printed after the table.
+func (e *Executor) renderQuery(query string, args ...interface{}) (string, error) {
+	now := time.Now()
+	rows, err := e.DB.Query(query, args...)
+	if err != nil {
+		return "", err
+	}
+	timeDif := time.Since(now).Seconds()
+	defer rows.Close()
+	rowCount, err := renderRows(e.out(), rows)
+	if err != nil {
+		return "", err
+	}
+	return e.rowsReturned(rowCount, timeDif), nil
+}
+
+// rowsReturned records the row count of the last result set and formats the
+// summary line printed after it.
+func (e *Executor) rowsReturned(rowCount int, seconds float64) string {
+	e.rowCount = rowCount
+	return fmt.Sprintf("\n%d rows returned in set (%.3f Sec)", rowCount, seconds)
+}
+
+// out returns the writer results are rendered to, stdout unless Out is set.
+func (e *Executor) out() io.Writer {
+	if e.Out == nil {
+		return os.Stdout
+	}
+	return e.Out
+}
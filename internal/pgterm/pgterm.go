@@ -5,11 +5,17 @@ package pgterm
 type SessionContext struct {
 	ActiveSchema   string // The currently selected schema (e.g., "public")
 	ActiveDatabase string // The currently selected database
+	DryRun         bool   // Data-modifying statements ask before they are committed
+	ReadOnly       bool   // Write statements are refused before they reach the server
+	Environment    string // Environment label of the connection: dev, staging, prod or empty
+	BreakGlass     bool   // Protected objects may be changed
+	Notices        bool   // NOTICE and WARNING messages of the server are printed
 }
 
 // session is the global session context initialized with the default schema "public".
 var session = &SessionContext{
 	ActiveSchema: "public",
+	Notices:      true,
 }
 
 // SetSchema sets the active schema for the session.
@@ -31,3 +37,53 @@ func (s *SessionContext) SetDatabase(database string) {
 func (s *SessionContext) GetDatabase() string {
 	return s.ActiveDatabase
 }
+
+// SetDryRun turns dry-run mode on or off.
+func (s *SessionContext) SetDryRun(dryRun bool) {
+	s.DryRun = dryRun
+}
+
+// GetDryRun reports whether dry-run mode is on.
+func (s *SessionContext) GetDryRun() bool {
+	return s.DryRun
+}
+
+// SetReadOnly turns read-only mode on or off.
+func (s *SessionContext) SetReadOnly(readOnly bool) {
+	s.ReadOnly = readOnly
+}
+
+// GetReadOnly reports whether the session is read-only.
+func (s *SessionContext) GetReadOnly() bool {
+	return s.ReadOnly
+}
+
+// SetEnvironment sets the environment label of the connection.
+func (s *SessionContext) SetEnvironment(env string) {
+	s.Environment = env
+}
+
+// GetEnvironment returns the environment label of the connection.
+func (s *SessionContext) GetEnvironment() string {
+	return s.Environment
+}
+
+// SetBreakGlass allows or forbids changes to protected objects.
+func (s *SessionContext) SetBreakGlass(breakGlass bool) {
+	s.BreakGlass = breakGlass
+}
+
+// GetBreakGlass reports whether protected objects may be changed.
+func (s *SessionContext) GetBreakGlass() bool {
+	return s.BreakGlass
+}
+
+// SetNotices turns printing of server notices on or off.
+func (s *SessionContext) SetNotices(notices bool) {
+	s.Notices = notices
+}
+
+// GetNotices reports whether server notices are printed.
+func (s *SessionContext) GetNotices() bool {
+	return s.Notices
+}
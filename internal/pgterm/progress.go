@@ -0,0 +1,228 @@
+package pgterm
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// progressSampleInterval is the time between the two samples SHOW PROGRESS takes
+// to work out the rate and ETA of each operation.
+const progressSampleInterval = time.Second
+
+// progressViews returns one SELECT per pg_stat_progress_* view available on the
+// server version. Every SELECT yields pid, operation, relation, phase, the units
+// done and their total, and the unit name.
+func progressViews(version int) []string {
+	views := []string{`
+    SELECT pid, 'VACUUM' AS operation, relid::regclass::text AS relation, phase,
+           CASE WHEN phase = 'vacuuming heap' THEN heap_blks_vacuumed ELSE heap_blks_scanned END AS done,
+           heap_blks_total AS total, 'blocks' AS units
+    FROM pg_stat_progress_vacuum`}
+	if version >= 120000 {
+		views = append(views, `
+    SELECT pid, command, relid::regclass::text, phase,
+           CASE WHEN blocks_total > 0 THEN blocks_done ELSE tuples_done END,
+           CASE WHEN blocks_total > 0 THEN blocks_total ELSE tuples_total END,
+           CASE WHEN blocks_total > 0 THEN 'blocks' ELSE 'tuples' END
+    FROM pg_stat_progress_create_index`, `
+    SELECT pid, command, relid::regclass::text, phase,
+           heap_blks_scanned, heap_blks_total, 'blocks'
+    FROM pg_stat_progress_cluster`)
+	}
+	if version >= 130000 {
+		views = append(views, `
+    SELECT pid, 'ANALYZE', relid::regclass::text, phase,
+           sample_blks_scanned, sample_blks_total, 'blocks'
+    FROM pg_stat_progress_analyze`, `
+    SELECT pid, 'BASE BACKUP', '', phase,
+           backup_streamed, COALESCE(backup_total, 0), 'bytes'
+    FROM pg_stat_progress_basebackup`)
+	}
+	if version >= 140000 {
+		views = append(views, `
+    SELECT pid, command || ' ' || type, COALESCE(relid::regclass::text, ''), '',
+           CASE WHEN bytes_total > 0 THEN bytes_processed ELSE tuples_processed END,
+           bytes_total,
+           CASE WHEN bytes_total > 0 THEN 'bytes' ELSE 'tuples' END
+    FROM pg_stat_progress_copy`)
+	}
+	return views
+}
+
+// progressSQL merges the progress views and adds how long each operation has run.
+func progressSQL(version int) string {
+	return fmt.Sprintf(`
+    SELECT p.pid, p.operation, COALESCE(p.relation, ''), p.phase, p.done, p.total, p.units,
+           COALESCE(EXTRACT(epoch FROM now() - a.query_start), 0)
+    FROM (%s) p
+    LEFT JOIN pg_stat_activity a ON a.pid = p.pid
+    ORDER BY p.pid;`, strings.Join(progressViews(version), "\n    UNION ALL"))
+}
+
+// progressSample is the state of a long running operation at one point in time.
+type progressSample struct {
+	PID       int
+	Operation string
+	Relation  string
+	Phase     string
+	Done      int64
+	Total     int64
+	Units     string
+	Elapsed   float64
+	At        time.Time
+}
+
+// Percent returns how much of the current phase is done, or -1 if the total is unknown.
+func (s progressSample) Percent() float64 {
+	if s.Total <= 0 {
+		return -1
+	}
+	return 100 * float64(s.Done) / float64(s.Total)
+}
+
+// progressRate returns the units per second between two samples of the same
+// operation and the seconds left at that rate. ok is false when the rate cannot
+// be worked out, e.g. because the phase changed in between.
+func progressRate(before, after progressSample) (rate float64, eta float64, ok bool) {
+	elapsed := after.At.Sub(before.At).Seconds()
+	if elapsed <= 0 || before.Phase != after.Phase || after.Done < before.Done {
+		return 0, 0, false
+	}
+	rate = float64(after.Done-before.Done) / elapsed
+	if rate <= 0 || after.Total <= 0 {
+		return rate, 0, false
+	}
+	return rate, float64(after.Total-after.Done) / rate, true
+}
+
+// sampleProgress reads the current state of every operation, keyed by pid.
+func (e *Executor) sampleProgress(query string) (map[int]progressSample, []int, error) {
+	rows, err := e.DB.Query(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	samples := map[int]progressSample{}
+	var order []int
+	now := time.Now()
+	for rows.Next() {
+		s := progressSample{At: now}
+		if err := rows.Scan(&s.PID, &s.Operation, &s.Relation, &s.Phase, &s.Done, &s.Total, &s.Units, &s.Elapsed); err != nil {
+			return nil, nil, err
+		}
+		if _, seen := samples[s.PID]; !seen {
+			order = append(order, s.PID)
+		}
+		samples[s.PID] = s
+	}
+	return samples, order, rows.Err()
+}
+
+// showProgress renders every running vacuum, analyze, index build, cluster, base
+// backup and COPY with percent done and an ETA from two consecutive samples.
+func (e *Executor) showProgress() (string, error) {
+	now := time.Now()
+	version, err := e.serverVersionNum()
+	if err != nil {
+		return "", err
+	}
+	query := progressSQL(version)
+	first, _, err := e.sampleProgress(query)
+	if err != nil {
+		return "", err
+	}
+	time.Sleep(progressSampleInterval)
+	second, order, err := e.sampleProgress(query)
+	if err != nil {
+		return "", err
+	}
+
+	table := newTable(e.out())
+	table.Header([]string{"pid", "operation", "relation", "phase", "progress", "done", "elapsed", "rate", "eta"})
+	for _, pid := range order {
+		s := second[pid]
+		progress, rate, eta := "", "", ""
+		if percent := s.Percent(); percent >= 0 {
+			progress = fmt.Sprintf("%.1f%%", percent)
+		}
+		if before, ok := first[pid]; ok {
+			if r, left, ok := progressRate(before, s); ok {
+				rate = fmt.Sprintf("%.0f %s/s", r, s.Units)
+				eta = formatSeconds(left)
+			} else if r > 0 {
+				rate = fmt.Sprintf("%.0f %s/s", r, s.Units)
+			}
+		}
+		table.Append([]string{strconv.Itoa(s.PID), s.Operation, s.Relation, s.Phase, progress,
+			fmt.Sprintf("%d/%d %s", s.Done, s.Total, s.Units), formatSeconds(s.Elapsed), rate, eta})
+	}
+	table.Render()
+	return e.rowsReturned(len(order), time.Since(now).Seconds()), nil
+}
+
+// followProgress draws a live progress bar for the operation of one backend
+// until it finishes or Ctrl-C is pressed.
+func (e *Executor) followProgress(pid int) error {
+	version, err := e.serverVersionNum()
+	if err != nil {
+		return err
+	}
+	query := progressSQL(version)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	var previous *progressSample
+	seen := false
+	for {
+		samples, _, err := e.sampleProgress(query)
+		if err != nil {
+			return err
+		}
+		s, ok := samples[pid]
+		if !ok {
+			if !seen {
+				return fmt.Errorf("process %d is not running a vacuum, analyze, index build, cluster, base backup or COPY", pid)
+			}
+			fmt.Println("\nOperation finished")
+			return nil
+		}
+		if !seen {
+			fmt.Printf("%s %s (pid %d)\n", s.Operation, s.Relation, pid)
+			seen = true
+		}
+		eta := ""
+		if previous != nil {
+			if _, left, ok := progressRate(*previous, s); ok {
+				eta = "ETA " + formatSeconds(left)
+			}
+		}
+		fmt.Printf("\r\x1b[K%s %s %s", progressBar(s.Percent(), 30), s.Phase, eta)
+		previous = &s
+
+		select {
+		case <-interrupt:
+			fmt.Println()
+			return nil
+		case <-time.After(progressSampleInterval):
+		}
+	}
+}
+
+// progressBar draws a bar of the given width for a percentage, e.g. [=====     ] 50.0%.
+// An unknown percentage (below zero) draws an empty bar.
+func progressBar(percent float64, width int) string {
+	if percent < 0 {
+		return "[" + strings.Repeat(" ", width) + "]      ?"
+	}
+	filled := int(percent / 100 * float64(width))
+	if filled > width {
+		filled = width
+	}
+	return fmt.Sprintf("[%s%s] %5.1f%%", greenText+strings.Repeat("=", filled)+resetText,
+		strings.Repeat(" ", width-filled), percent)
+}
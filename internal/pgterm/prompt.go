@@ -11,20 +11,37 @@ import (
 )
 
 type Prompt struct {
-	DB *sql.DB
+	DB         *sql.DB
+	DryRun     bool      // Start with dry-run mode on
+	ReadOnly   bool      // Refuse write statements, the session is opened read-only as well
+	Env        string    // Environment label shown in the prompt: dev, staging, prod or empty
+	Audit      *AuditLog // Records every statement, nil when auditing is off
+	BreakGlass bool      // Allow changes to protected objects
+	// Connection holds the settings of DB, used to open the connection of LISTEN.
+	// LISTEN is refused when it is nil.
+	Connection *Connection
 }
 
 var currentPrompt *prompt.Prompt
 var version, currentUser, currentDatabase string
 var buffer []string
 
+// lastStatement is the most recent statement that ran without error, re-run by \watch.
+var lastStatement string
+
 func (p *Prompt) New() {
-	p.DB.QueryRow("SELECT current_user, current_database(), version()").Scan(&currentUser, &currentDatabase, &version)
+	var inRecovery bool
+	p.DB.QueryRow("SELECT current_user, current_database(), version(), pg_is_in_recovery()").Scan(&currentUser, &currentDatabase, &version, &inRecovery)
+	serverRole := "primary"
+	if inRecovery {
+		serverRole = "standby (read-only)"
+	}
 	fmt.Print("\n")
 	fmt.Println(fmt.Sprintf(`
 Welcome to the PgTerm PostgresSQL CLI client.  Commands end with ;.
 Your PostgreSQL user ID is %s
 Server version: PostgreSQL %s
+Server role: %s
 
 Copyright (c) 2025 Matt Sebuuma
 
@@ -32,26 +49,38 @@ This software includes open source components licensed under their respective te
 
 Licensed under the MIT License.
 
-Type 'help;' or '\h' for help.`, currentUser, extractPostgresVersion(version)))
+Type 'help;' or '\h' for help.`, currentUser, extractPostgresVersion(version), serverRole))
 	session.SetDatabase(currentDatabase)
-	fmt.Println("\n")
+	session.SetDryRun(p.DryRun)
+	session.SetReadOnly(p.ReadOnly)
+	session.SetEnvironment(p.Env)
+	auditLog = p.Audit
+	listenConnection = p.Connection
+	session.SetBreakGlass(p.BreakGlass)
+	if p.BreakGlass {
+		fmt.Print("\n" + whiteOnRed + boldText + " BREAK GLASS: protected objects can be dropped and truncated in this session " + resetText)
+	}
+	fmt.Print(environmentBanner(p.Env))
+	if p.ReadOnly {
+		fmt.Print("\nRead-only session: write statements are refused.")
+	}
+	if p.DryRun {
+		fmt.Print("\nDry run is on: data changes ask before they are committed, \\dryrun off to turn it off.")
+	}
+	fmt.Print("\n\n")
 
-	currentPrompt = prompt.New(p.executor, p.completer, prompt.OptionPrefix(fmt.Sprintf("pgterm [%s.%s]> ", session.GetDatabase(), session.GetSchema())),
-		prompt.OptionPrefixTextColor(prompt.Green),
+	currentPrompt = prompt.New(p.executor, p.completer, prompt.OptionPrefix(promptPrefix()),
+		prompt.OptionPrefixTextColor(environmentColor(session.GetEnvironment())),
 		prompt.OptionPreviewSuggestionTextColor(prompt.Blue),
 		prompt.OptionSelectedSuggestionBGColor(prompt.LightGray),
 		prompt.OptionSuggestionBGColor(prompt.DarkGray),
-		prompt.OptionLivePrefix(func() (string, bool) {
-			// Show different prefix if collecting multiline input
-			if len(buffer) > 0 {
-				return "... ", true
-			}
-			return fmt.Sprintf("pgterm [%s.%s]> ", session.GetDatabase(), session.GetSchema()), true
-		}))
+		prompt.OptionLivePrefix(livePrefix),
+		prompt.OptionWriter(promptWriter{prompt.NewStandardOutputWriter()}))
 	currentPrompt.Run()
 }
 
 func (p *Prompt) completer(in prompt.Document) []prompt.Suggest {
+	trackInput(in)
 	if len(buffer) > 0 {
 		return nil
 	}
@@ -60,7 +89,13 @@ func (p *Prompt) completer(in prompt.Document) []prompt.Suggest {
 }
 
 func (p *Prompt) executor(input string) {
+	startExecuting()
+	defer stopExecuting()
 	input = strings.TrimSpace(input)
+	if isMetaCommand(input) {
+		p.metaCommand(input)
+		return
+	}
 	// ensure that all input has a termination at the end
 	if len(input) > 0 {
 		termination := input[len(input)-1:]
@@ -70,6 +105,10 @@ func (p *Prompt) executor(input string) {
 			switch input {
 			case "exit;", "quit;":
 				p.DB.Close()
+				p.Audit.Close()
+				if listener != nil {
+					listener.Close()
+				}
 				fmt.Println("Goodbye!")
 				os.Exit(0)
 			case "help;":
@@ -87,7 +126,9 @@ func (p *Prompt) executor(input string) {
 					}
 					resp, promptResetRequired, err := executor.Execute(full)
 					if err != nil {
-						fmt.Println(err.Error())
+						fmt.Println(formatError(err, full, executor.finalSQL))
+					} else {
+						lastStatement = full
 					}
 					fmt.Println(resp)
 					if promptResetRequired {
@@ -102,22 +143,43 @@ func (p *Prompt) executor(input string) {
 }
 
 func (p *Prompt) restartPrompt() {
+	// The new prompt runs inside the executor of the old one, whose deferred
+	// stopExecuting only runs when it returns, so notifications would be held
+	// back until the next command.
+	stopExecuting()
 	// Stop old prompt
-	currentPrompt = prompt.New(p.executor, p.completer, prompt.OptionPrefix(fmt.Sprintf("pgterm [%s.%s]> ", session.GetDatabase(), session.GetSchema())),
-		prompt.OptionPrefixTextColor(prompt.Green),
+	currentPrompt = prompt.New(p.executor, p.completer, prompt.OptionPrefix(promptPrefix()),
+		prompt.OptionPrefixTextColor(environmentColor(session.GetEnvironment())),
 		prompt.OptionPreviewSuggestionTextColor(prompt.Blue),
 		prompt.OptionSelectedSuggestionBGColor(prompt.LightGray),
 		prompt.OptionSuggestionBGColor(prompt.DarkGray),
-		prompt.OptionLivePrefix(func() (string, bool) {
-			// Show different prefix if collecting multiline input
-			if len(buffer) > 0 {
-				return "... ", true
-			}
-			return fmt.Sprintf("pgterm [%s.%s]> ", session.GetDatabase(), session.GetSchema()), true
-		}))
+		prompt.OptionLivePrefix(livePrefix),
+		prompt.OptionWriter(promptWriter{prompt.NewStandardOutputWriter()}))
 	currentPrompt.Run()
 }
 
+// promptPrefix returns the prompt, e.g. "pgterm [mydb.public]> ", with the
+// environment label and an [RO] marker for read-only sessions.
+func promptPrefix() string {
+	prefix := fmt.Sprintf("pgterm [%s.%s]> ", session.GetDatabase(), session.GetSchema())
+	if session.GetReadOnly() {
+		prefix = "[RO] " + prefix
+	}
+	if env := session.GetEnvironment(); env != "" {
+		prefix = "[" + env + "] " + prefix
+	}
+	return prefix
+}
+
+// livePrefix is the OptionLivePrefix callback of the prompt.
+func livePrefix() (string, bool) {
+	// Show different prefix if collecting multiline input
+	if len(buffer) > 0 {
+		return "... ", true
+	}
+	return promptPrefix(), true
+}
+
 func extractPostgresVersion(input string) string {
 	re := regexp.MustCompile(`PostgreSQL\s+(\d+\.\d+)`)
 	match := re.FindStringSubmatch(input)
@@ -127,8 +189,9 @@ func extractPostgresVersion(input string) string {
 	return ""
 }
 
-func askConfirmation(keyWord string) bool {
-	fmt.Println(fmt.Sprintf("WARNING: Your %s statement has NO WHERE clause.", keyWord))
+// confirm prints the warning and asks the user whether to go ahead.
+func confirm(warning string) bool {
+	fmt.Println(warning)
 	answer := prompt.Input("Are you sure you want to continue? (yes/no): ", func(d prompt.Document) []prompt.Suggest {
 		return []prompt.Suggest{}
 	})
@@ -136,3 +199,29 @@ func askConfirmation(keyWord string) bool {
 	answer = strings.ToLower(strings.TrimSpace(answer))
 	return answer == "yes" || answer == "y"
 }
+
+// confirmByName prints the warning and asks the user to type the name of each
+// object, as a stronger confirmation for the most destructive statements.
+func confirmByName(warning string, names []string) bool {
+	fmt.Println(warning)
+	for _, name := range names {
+		answer := prompt.Input(fmt.Sprintf("Type %s to continue: ", name), func(d prompt.Document) []prompt.Suggest {
+			return []prompt.Suggest{}
+		})
+		if strings.TrimSpace(answer) != name {
+			return false
+		}
+	}
+	return true
+}
+
+// confirmCommit prints the warning and asks whether to commit or roll back the
+// open transaction. Anything but commit rolls it back.
+func confirmCommit(warning string) bool {
+	fmt.Println(warning)
+	answer := prompt.Input("Commit or roll back? (commit/rollback): ", func(d prompt.Document) []prompt.Suggest {
+		return []prompt.Suggest{}
+	})
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "commit" || answer == "c"
+}
@@ -0,0 +1,115 @@
+package pgterm
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// resolveRelationSQL resolves a relation name through the search_path of the server.
+const resolveRelationSQL = `
+    SELECT n.nspname || '.' || c.relname
+    FROM pg_class c
+    JOIN pg_namespace n ON n.oid = c.relnamespace
+    WHERE c.oid = to_regclass($1);`
+
+// destroyedObjects returns the schema-qualified tables a statement would drop,
+// empty or alter with DROP, and the schemas it would drop. Table names are
+// qualified with resolve.
+func destroyedObjects(s *statement, resolve func(name string) string) ([]string, []string) {
+	var tables, schemas []string
+	switch {
+	case s.Verb == "DROP" && s.ObjectType == "SCHEMA":
+		for _, target := range s.Targets {
+			if parts := splitName(target); len(parts) > 0 {
+				schemas = append(schemas, parts[len(parts)-1])
+			}
+		}
+		return nil, schemas
+	case s.Verb == "DROP" && (s.ObjectType == "TABLE" || s.ObjectType == "FOREIGN TABLE" ||
+		s.ObjectType == "VIEW" || s.ObjectType == "MATERIALIZED VIEW"),
+		s.Verb == "TRUNCATE",
+		s.Verb == "ALTER" && s.Drops,
+		s.Verb == "DELETE" && !s.Filtered:
+		for _, target := range s.Targets {
+			if name := resolve(target); name != "" {
+				tables = append(tables, name)
+			}
+		}
+	}
+	return tables, nil
+}
+
+// qualifiedName resolves a table name to schema.table, using the active schema
+// when the name is not qualified.
+func qualifiedName(name string) string {
+	parts := splitName(name)
+	switch len(parts) {
+	case 0:
+		return ""
+	case 1:
+		return session.GetSchema() + "." + parts[0]
+	}
+	return parts[len(parts)-2] + "." + parts[len(parts)-1]
+}
+
+// protectingPattern returns the pattern of the protected list that covers the
+// object. A pattern without a dot protects a whole schema, e.g. billing; other
+// patterns match schema.table names, e.g. billing.* or public.payments. A schema
+// is protected when any pattern covers it or tables in it.
+func protectingPattern(name string, isSchema bool) (string, bool) {
+	for _, pattern := range config.Protected {
+		schemaPattern, tablePattern, qualified := strings.Cut(pattern, ".")
+		var ok bool
+		switch {
+		case isSchema:
+			ok, _ = path.Match(schemaPattern, name)
+		case !qualified:
+			schema, _, _ := strings.Cut(name, ".")
+			ok, _ = path.Match(schemaPattern, schema)
+		default:
+			schema, table, _ := strings.Cut(name, ".")
+			schemaOK, _ := path.Match(schemaPattern, schema)
+			tableOK, _ := path.Match(tablePattern, table)
+			ok = schemaOK && tableOK
+		}
+		if ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// resolveRelation returns the schema.table a table name of a statement refers to
+// when it is executed: unqualified names are resolved by the server through its
+// search_path, like the statement itself. Names the server does not know are
+// qualified with the active schema.
+func (e *Executor) resolveRelation(name string) string {
+	var resolved string
+	if err := e.DB.QueryRow(resolveRelationSQL, name).Scan(&resolved); err != nil {
+		return qualifiedName(name)
+	}
+	return resolved
+}
+
+// checkProtected refuses to drop, truncate, alter with DROP or empty with an
+// unfiltered DELETE any protected object, unless the session was started with
+// --break-glass.
+func (e *Executor) checkProtected(s *statement) error {
+	if len(config.Protected) == 0 {
+		return nil
+	}
+	tables, schemas := destroyedObjects(s, e.resolveRelation)
+	for i, name := range append(tables, schemas...) {
+		pattern, protected := protectingPattern(name, i >= len(tables))
+		if !protected {
+			continue
+		}
+		if session.GetBreakGlass() {
+			fmt.Printf("%sBreak glass: %s is protected by %q%s\n", redText, name, pattern, resetText)
+			continue
+		}
+		return fmt.Errorf("%s is protected by %q in the config file, start pgterm with --break-glass to change it", name, pattern)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package pgterm
+
+// replicationPrimarySQL lists the standbys streaming from this primary with
+// their lag in bytes and time.
+const replicationPrimarySQL = `
+    SELECT pid,
+           usename AS user,
+           application_name,
+           COALESCE(host(client_addr), 'local') AS client,
+           state,
+           sync_state,
+           pg_size_pretty(pg_wal_lsn_diff(pg_current_wal_lsn(), sent_lsn)) AS send_lag_size,
+           pg_size_pretty(pg_wal_lsn_diff(pg_current_wal_lsn(), write_lsn)) AS write_lag_size,
+           pg_size_pretty(pg_wal_lsn_diff(pg_current_wal_lsn(), flush_lsn)) AS flush_lag_size,
+           pg_size_pretty(pg_wal_lsn_diff(pg_current_wal_lsn(), replay_lsn)) AS replay_lag_size,
+           COALESCE(write_lag::text, '') AS write_lag,
+           COALESCE(flush_lag::text, '') AS flush_lag,
+           COALESCE(replay_lag::text, '') AS replay_lag
+    FROM pg_stat_replication
+    ORDER BY application_name, pid;`
+
+// replicationStandbySQL shows the WAL receiver of this standby and how far
+// replay is behind what has been received.
+const replicationStandbySQL = `
+    SELECT pid,
+           status,
+           COALESCE(sender_host, '') AS sender_host,
+           sender_port,
+           COALESCE(slot_name, '') AS slot_name,
+           pg_last_wal_receive_lsn()::text AS received_lsn,
+           pg_last_wal_replay_lsn()::text AS replayed_lsn,
+           pg_size_pretty(pg_wal_lsn_diff(pg_last_wal_receive_lsn(), pg_last_wal_replay_lsn())) AS replay_lag_size,
+           COALESCE(date_trunc('second', now() - pg_last_xact_replay_timestamp())::text, '') AS replay_delay,
+           COALESCE(to_char(last_msg_receipt_time, 'YYYY-MM-DD HH24:MI:SS'), '') AS last_message
+    FROM pg_stat_wal_receiver;`
+
+// replicationSlotsSQL lists replication slots with the WAL they retain, largest
+// first, and flags inactive slots that hold WAL back.
+const replicationSlotsSQL = `
+    SELECT slot_name,
+           slot_type,
+           COALESCE(plugin, '') AS plugin,
+           COALESCE(database, '') AS database,
+           active,
+           COALESCE(active_pid::text, '') AS active_pid,
+           COALESCE(pg_size_pretty(retained), '') AS retained_wal,
+           CASE WHEN NOT active AND retained > 0 THEN 'inactive slot is holding back WAL' ELSE '' END AS warning
+    FROM (
+        SELECT *,
+               pg_wal_lsn_diff(CASE WHEN pg_is_in_recovery() THEN pg_last_wal_receive_lsn()
+                                    ELSE pg_current_wal_lsn() END, restart_lsn) AS retained
+        FROM pg_replication_slots
+    ) slots
+    ORDER BY retained DESC NULLS LAST, slot_name;`
+
+// showReplication renders the standbys of a primary, or the WAL receiver status
+// when connected to a standby.
+func (e *Executor) showReplication() (string, error) {
+	var standby bool
+	if err := e.DB.QueryRow("SELECT pg_is_in_recovery();").Scan(&standby); err != nil {
+		return "", err
+	}
+	if standby {
+		return e.renderQuery(replicationStandbySQL)
+	}
+	return e.renderQuery(replicationPrimarySQL)
+}
+
+// showSlots renders the replication slots.
+func (e *Executor) showSlots() (string, error) {
+	return e.renderQuery(replicationSlotsSQL)
+}
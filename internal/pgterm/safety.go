@@ -5,46 +5,218 @@ import (
 	"strings"
 )
 
-// confirmIfNoWhere inspects the SQL and prompts if UPDATE or DELETE has no WHERE
-func confirmIfNoWhere(sql string) (bool, error) {
-	sqlUpper := strings.ToUpper(sql)
-	sqlTrim := strings.TrimSpace(sqlUpper)
-
-	isUpdate := strings.HasPrefix(sqlTrim, "UPDATE")
-	isDelete := strings.HasPrefix(sqlTrim, "DELETE")
-
-	if !isUpdate && !isDelete {
-		// Not update/delete, allow directly
-		return true, nil
-	}
-
-	// crude check: does it have " WHERE " (with spaces) after the first word?
-	// This simple approach avoids false positives like column names
-	hasWhere := false
-	words := strings.Fields(sqlUpper)
-	for i, w := range words {
-		if (isUpdate && w == "UPDATE") || (isDelete && w == "DELETE") {
-			// look for WHERE after this position
-			for _, w2 := range words[i+1:] {
-				if w2 == "WHERE" {
-					hasWhere = true
-					break
-				}
+// Actions of a guard rule.
+const (
+	guardAllow   = "allow"   // run the statement
+	guardConfirm = "confirm" // ask before running it
+	guardDeny    = "deny"    // refuse to run it
+)
+
+// guardRule is a check of the destructive statement guard. match returns the
+// objects a statement would destroy, or nothing when the rule does not apply.
+type guardRule struct {
+	Name     string
+	Action   string // default action, overridden by the guard section of the config
+	TypeName bool   // confirming requires typing the name of each object
+	Warning  string // %s is replaced by the objects
+	match    func(s *statement) []string
+}
+
+// guardRules are checked against every statement, including data-modifying
+// statements in WITH and the statement of EXPLAIN ANALYZE.
+var guardRules = []guardRule{
+	{
+		Name:    "delete-without-where",
+		Action:  guardConfirm,
+		Warning: "WARNING: Your DELETE statement on %s has NO WHERE clause.",
+		match: func(s *statement) []string {
+			if s.Verb == "DELETE" && !s.Filtered {
+				return targetsOrUnknown(s)
+			}
+			return nil
+		},
+	},
+	{
+		Name:    "update-without-where",
+		Action:  guardConfirm,
+		Warning: "WARNING: Your UPDATE statement on %s has NO WHERE clause.",
+		match: func(s *statement) []string {
+			if s.Verb == "UPDATE" && !s.Filtered {
+				return targetsOrUnknown(s)
 			}
-			break
+			return nil
+		},
+	},
+	{
+		Name:    "truncate",
+		Action:  guardConfirm,
+		Warning: "WARNING: TRUNCATE removes every row of %s.",
+		match: func(s *statement) []string {
+			if s.Verb == "TRUNCATE" {
+				return targetsOrUnknown(s)
+			}
+			return nil
+		},
+	},
+	{
+		Name:     "drop-table",
+		Action:   guardConfirm,
+		TypeName: true,
+		Warning:  "WARNING: DROP TABLE removes %s and all of its data.",
+		match:    dropOf("TABLE", "FOREIGN TABLE", "MATERIALIZED VIEW"),
+	},
+	{
+		Name:     "drop-schema",
+		Action:   guardConfirm,
+		TypeName: true,
+		Warning:  "WARNING: DROP SCHEMA removes %s and everything in it.",
+		match:    dropOf("SCHEMA"),
+	},
+	{
+		Name:     "drop-database",
+		Action:   guardConfirm,
+		TypeName: true,
+		Warning:  "WARNING: DROP DATABASE removes %s and all of its data.",
+		match:    dropOf("DATABASE"),
+	},
+	{
+		Name:    "drop-column",
+		Action:  guardConfirm,
+		Warning: "WARNING: ALTER TABLE drops the column %s and its data.",
+		match: func(s *statement) []string {
+			if s.Verb != "ALTER" || len(s.Columns) == 0 {
+				return nil
+			}
+			table := strings.Join(targetsOrUnknown(s), "")
+			var columns []string
+			for _, c := range s.Columns {
+				columns = append(columns, table+"."+c)
+			}
+			return columns
+		},
+	},
+}
+
+// dropOf matches DROP statements of the given object types.
+func dropOf(objectTypes ...string) func(s *statement) []string {
+	return func(s *statement) []string {
+		if s.Verb != "DROP" {
+			return nil
 		}
+		for _, t := range objectTypes {
+			if s.ObjectType == t {
+				return targetsOrUnknown(s)
+			}
+		}
+		return nil
+	}
+}
+
+// targetsOrUnknown returns the targets of the statement, never an empty list, so
+// a rule still matches when the classifier could not read the object name.
+func targetsOrUnknown(s *statement) []string {
+	if len(s.Targets) == 0 {
+		return []string{"an unknown object"}
+	}
+	return s.Targets
+}
+
+// findGuardRule returns the rule with the given name, or nil.
+func findGuardRule(name string) *guardRule {
+	for i := range guardRules {
+		if guardRules[i].Name == name {
+			return &guardRules[i]
+		}
+	}
+	return nil
+}
+
+// action returns the configured action of the rule.
+func (r *guardRule) action() string {
+	if action, ok := config.Guard[r.Name]; ok {
+		return action
 	}
-	if hasWhere {
-		return true, nil
+	return r.Action
+}
+
+// readOnlyVerbs are the statements allowed in a read-only session.
+var readOnlyVerbs = map[string]bool{
+	"": true, "SELECT": true, "SHOW": true, "EXPLAIN": true, "VALUES": true, "TABLE": true,
+	"SET": true, "RESET": true, "BEGIN": true, "START": true, "COMMIT": true, "END": true,
+	"ROLLBACK": true, "ABORT": true, "SAVEPOINT": true, "RELEASE": true, "FETCH": true,
+	"MOVE": true, "DECLARE": true, "CLOSE": true, "DISCARD": true, "LISTEN": true,
+	"UNLISTEN": true, "DEALLOCATE": true,
+}
+
+// readOnlySettings are the settings a read-only session may not change.
+var readOnlySettings = map[string]bool{
+	"default_transaction_read_only": true,
+	"transaction_read_only":         true,
+}
+
+// checkReadOnly refuses statements that could write in a read-only session. The
+// server refuses them too, since the session starts with
+// default_transaction_read_only on; this catches them before they are sent.
+func checkReadOnly(s *statement) error {
+	if !readOnlyVerbs[s.Verb] {
+		return fmt.Errorf("Read-only session: %s statements are not allowed", s.Verb)
+	}
+	if s.ReadWrite {
+		return fmt.Errorf("Read-only session: READ WRITE transactions are not allowed")
 	}
-	if isUpdate {
-		if askConfirmation("UPDATE") {
-			return true, nil
+	// setting names are not case sensitive, even when quoted
+	if s.Verb == "SET" && len(s.Targets) > 0 && readOnlySettings[strings.ToLower(s.Targets[0])] {
+		return fmt.Errorf("Read-only session: %s cannot be changed", s.Targets[0])
+	}
+	for _, name := range s.SetConfig {
+		if name == "" {
+			return fmt.Errorf("Read-only session: set_config needs a literal setting name")
+		}
+		if readOnlySettings[name] {
+			return fmt.Errorf("Read-only session: %s cannot be changed", name)
 		}
-	} else if isDelete {
-		if askConfirmation("DELETE") {
-			return true, nil
+	}
+	return nil
+}
+
+// checkStatement is run before any SQL is sent to the database. It classifies the
+// statements, refuses writes in a read-only session and changes to protected
+// objects, asks before DDL in production and applies the guard rules, asking for
+// confirmation or refusing the SQL as configured.
+func (e *Executor) checkStatement(sql string) error {
+	for _, top := range classifySQL(sql) {
+		for _, s := range top.all() {
+			if session.GetReadOnly() {
+				if err := checkReadOnly(s); err != nil {
+					return err
+				}
+			}
+			if err := e.checkProtected(s); err != nil {
+				return err
+			}
+			if err := checkProduction(s); err != nil {
+				return err
+			}
+			for i := range guardRules {
+				rule := &guardRules[i]
+				objects := rule.match(s)
+				if len(objects) == 0 {
+					continue
+				}
+				switch rule.action() {
+				case guardDeny:
+					return fmt.Errorf("%s is not allowed by the guard rule %s", s.Verb, rule.Name)
+				case guardConfirm:
+					warning := fmt.Sprintf(rule.Warning, strings.Join(objects, ", "))
+					if rule.TypeName && !confirmByName(warning, objects) {
+						return fmt.Errorf("Names did not match. Query cancelled")
+					}
+					if !rule.TypeName && !confirm(warning) {
+						return fmt.Errorf("Safe choice. Query cancelled")
+					}
+				}
+			}
 		}
 	}
-	return false, fmt.Errorf("Safe choice. Query cancelled")
+	return nil
 }
@@ -0,0 +1,124 @@
+package pgterm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	tests := []struct {
+		sql     string
+		allowed bool
+	}{
+		{"SELECT * FROM orders", true},
+		{"EXPLAIN SELECT 1", true},
+		{"SET work_mem = '64MB'", true},
+		{"SET SESSION statement_timeout TO 0", true},
+		{"BEGIN", true},
+		{"BEGIN READ ONLY", true},
+		{"SELECT set_config('work_mem', '64MB', false)", true},
+		{"DELETE FROM orders WHERE id = 1", false},
+		{"INSERT INTO orders VALUES (1)", false},
+		{"CREATE TABLE t (a int)", false},
+		{"DROP TABLE orders", false},
+		{"SET default_transaction_read_only = off", false},
+		{"SET SESSION \"Default_Transaction_Read_Only\" TO off", false}, // setting names ignore case, even quoted
+		{"set DEFAULT_TRANSACTION_READ_ONLY to off", false},
+		{"SET transaction_read_only = off", false},
+		{"BEGIN READ WRITE", false},
+		{"START TRANSACTION ISOLATION LEVEL SERIALIZABLE, READ WRITE", false},
+		{"SET SESSION CHARACTERISTICS AS TRANSACTION READ WRITE", false},
+		{"SELECT set_config('default_transaction_read_only', 'off', false)", false},
+		{"SELECT set_config('TRANSACTION_READ_ONLY', 'off', true)", false},
+		{"SELECT set_config($$default_transaction_read_only$$, 'off', false)", false},
+		{"SELECT set_config(name, 'off', false) FROM pg_settings", false},
+		{"SELECT * FROM (SELECT set_config('transaction_read_only', 'off', true)) s", false},
+	}
+	for _, tt := range tests {
+		var err error
+		for _, top := range classifySQL(tt.sql) {
+			for _, s := range top.all() {
+				if err == nil {
+					err = checkReadOnly(s)
+				}
+			}
+		}
+		if (err == nil) != tt.allowed {
+			t.Errorf("checkReadOnly(%q) = %v, want allowed %v", tt.sql, err, tt.allowed)
+		}
+	}
+}
+
+func TestGuardRules(t *testing.T) {
+	tests := []struct {
+		sql     string
+		rule    string
+		objects []string // nil when the rule does not match
+	}{
+		{"DELETE FROM orders", "delete-without-where", []string{"orders"}},
+		{"DELETE FROM orders WHERE 1 = 1", "delete-without-where", []string{"orders"}},
+		{"DELETE FROM orders WHERE id = 1", "delete-without-where", nil},
+		{"UPDATE orders SET paid = true", "update-without-where", []string{"orders"}},
+		{"UPDATE orders SET paid = true WHERE true", "update-without-where", []string{"orders"}},
+		{"UPDATE orders SET paid = true WHERE id = 1", "update-without-where", nil},
+		{"TRUNCATE a, b", "truncate", []string{"a", "b"}},
+		{"DROP TABLE IF EXISTS a, b", "drop-table", []string{"a", "b"}},
+		{"DROP MATERIALIZED VIEW mv", "drop-table", []string{"mv"}},
+		{"DROP VIEW v", "drop-table", nil},
+		{"DROP SCHEMA billing CASCADE", "drop-schema", []string{"billing"}},
+		{"DROP DATABASE shop", "drop-database", []string{"shop"}},
+		{"DROP TABLE shop", "drop-database", nil},
+		{"ALTER TABLE orders DROP COLUMN total, DROP note", "drop-column", []string{"orders.total", "orders.note"}},
+		{"ALTER TABLE orders DROP CONSTRAINT c", "drop-column", nil},
+	}
+	for _, tt := range tests {
+		rule := findGuardRule(tt.rule)
+		if rule == nil {
+			t.Fatalf("unknown guard rule %s", tt.rule)
+		}
+		if got := rule.match(classifySQL(tt.sql)[0]); !reflect.DeepEqual(got, tt.objects) {
+			t.Errorf("rule %s on %q matched %q, want %q", tt.rule, tt.sql, got, tt.objects)
+		}
+	}
+}
+
+func TestGuardRuleActions(t *testing.T) {
+	defer func(guard map[string]string) { config.Guard = guard }(config.Guard)
+	defer func(readOnly bool) { session.SetReadOnly(readOnly) }(session.GetReadOnly())
+	session.SetReadOnly(false)
+	e := &Executor{}
+
+	// nested statements are checked too, so none of these gets past a deny rule
+	tests := []struct {
+		sql  string
+		rule string
+	}{
+		{"DELETE FROM orders", "delete-without-where"},
+		{"WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d", "delete-without-where"},
+		{"EXPLAIN ANALYZE UPDATE orders SET x = 1", "update-without-where"},
+		{"SELECT 1; TRUNCATE orders", "truncate"},
+		{"DROP TABLE orders", "drop-table"},
+		{"DROP SCHEMA billing", "drop-schema"},
+		{"DROP DATABASE shop", "drop-database"},
+		{"ALTER TABLE orders DROP COLUMN total", "drop-column"},
+	}
+	for _, tt := range tests {
+		config.Guard = map[string]string{tt.rule: guardDeny}
+		err := e.checkStatement(tt.sql)
+		if err == nil || !strings.Contains(err.Error(), tt.rule) {
+			t.Errorf("checkStatement(%q) with %s denied = %v, want an error naming the rule", tt.sql, tt.rule, err)
+		}
+		config.Guard = map[string]string{tt.rule: guardAllow}
+		if err := e.checkStatement(tt.sql); err != nil {
+			t.Errorf("checkStatement(%q) with %s allowed = %v, want nil", tt.sql, tt.rule, err)
+		}
+	}
+
+	config.Guard = nil
+	for _, rule := range guardRules {
+		if rule.action() != rule.Action {
+			t.Errorf("rule %s action = %s without configuration, want the default %s", rule.Name, rule.action(), rule.Action)
+		}
+	}
+}
@@ -14,42 +14,113 @@ var sqlKeywords = map[string]bool{
 	"outer": true, "group": true, "by": true, "order": true, "limit": true,
 }
 
-// addSchema prefixes table names with schema if not already qualified
+// addSchema prefixes table names with schema if not already qualified. It works
+// on the tokens of lexSQL, so string literals, comments, subqueries and function
+// calls such as generate_series(1, 3) are left alone, and it only inserts the
+// schema prefix and capitalizes keywords: everything else is sent as typed.
 func addSchema(sql, schema string) string {
-	tokens := strings.Fields(sql)
+	tokens := lexSQL(sql)
 	keywords := map[string]bool{
 		"FROM": true, "JOIN": true,
 		"UPDATE": true, "INTO": true,
 		"DELETE": true, "TABLE": true,
 	}
+	ctes := cteNames(tokens)
+	prefix := quoteIdent(schema) + "."
 
-	for i := 0; i < len(tokens); i++ {
-		upper := strings.ToUpper(tokens[i])
-		if keywords[upper] {
-			// get next token as table name if exists
-			if i+1 < len(tokens) {
-				tbl := tokens[i+1]
-
-				// strip alias punctuation
-				tblClean := strings.TrimFunc(tbl, func(r rune) bool {
-					return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.'
-				})
-
-				// skip if already schema-qualified or subquery
-				if !strings.Contains(tblClean, ".") && !strings.HasPrefix(tblClean, "(") {
-					tokens[i+1] = strings.Replace(tokens[i+1], tblClean, schema+"."+tblClean, 1)
-				}
+	// queries tells for each open parenthesis whether it holds a query, where FROM
+	// names a table, rather than e.g. extract(year FROM ts)
+	var queries []bool
+	qualify := map[int]bool{} // tokens to prefix with the schema
+	for i, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			queries = append(queries, i+1 < len(tokens) && startsQuery(tokens[i+1]))
+			continue
+		case t.isPunct(")"):
+			if len(queries) > 0 {
+				queries = queries[:len(queries)-1]
 			}
+			continue
+		}
+		if t.kind != tokenWord || !keywords[strings.ToUpper(t.text)] || (len(queries) > 0 && !queries[len(queries)-1]) {
+			continue
+		}
+		// IS DISTINCT FROM compares values, FOR UPDATE locks rows
+		if i > 0 && (tokens[i-1].is("DISTINCT") || (t.is("UPDATE") && tokens[i-1].is("FOR"))) {
+			continue
+		}
+		j := skipWords(tokens, i+1, "ONLY", "IF", "NOT", "EXISTS")
+		if j >= len(tokens) || (tokens[j].kind != tokenWord && tokens[j].kind != tokenQuoted) {
+			continue
 		}
+		name := tokens[j]
+		// keywords are not table names, e.g. FROM in DELETE FROM
+		if name.kind == tokenWord && (keywords[strings.ToUpper(name.text)] || sqlKeywords[strings.ToLower(name.text)]) {
+			continue
+		}
+		// skip qualified names, function calls and common table expressions; after
+		// TABLE and INTO a parenthesis holds the columns
+		if j+1 < len(tokens) && (tokens[j+1].isPunct(".") || (tokens[j+1].isPunct("(") && (t.is("FROM") || t.is("JOIN")))) {
+			continue
+		}
+		if name.kind == tokenWord && ctes[strings.ToLower(name.text)] {
+			continue
+		}
+		qualify[j] = true
 	}
-	// Capitalize keywords
-	for i := range tokens {
-		if sqlKeywords[strings.ToLower(tokens[i])] {
-			tokens[i] = strings.ToUpper(tokens[i])
+
+	var b strings.Builder
+	copied := 0
+	for i, t := range tokens {
+		switch {
+		case qualify[i]:
+			b.WriteString(sql[copied:t.pos])
+			b.WriteString(prefix)
+			copied = t.pos
+		case t.kind == tokenWord && sqlKeywords[strings.ToLower(t.text)]:
+			// capitalize keywords
+			b.WriteString(sql[copied:t.pos])
+			b.WriteString(strings.ToUpper(t.text))
+			copied = t.pos + len(t.text)
 		}
 	}
+	b.WriteString(sql[copied:])
+	return b.String()
+}
 
-	return strings.Join(tokens, " ")
+// startsQuery reports whether the token starts a query inside parentheses, e.g.
+// a subquery or the body of a common table expression.
+func startsQuery(t sqlToken) bool {
+	for _, verb := range []string{"SELECT", "WITH", "VALUES", "TABLE", "INSERT", "UPDATE", "DELETE", "MERGE"} {
+		if t.is(verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// cteNames returns the lower case names of the common table expressions defined
+// in a WITH clause anywhere in the tokens: name [(columns)] AS [NOT] [MATERIALIZED] (.
+func cteNames(tokens []sqlToken) map[string]bool {
+	names := map[string]bool{}
+	for i := 1; i+1 < len(tokens); i++ {
+		previous := tokens[i-1]
+		if tokens[i].kind != tokenWord || !(previous.is("WITH") || previous.is("RECURSIVE") || previous.isPunct(",")) {
+			continue
+		}
+		j := i + 1
+		if tokens[j].isPunct("(") {
+			j = closingParen(tokens, j) + 1
+		}
+		if j < len(tokens) && tokens[j].is("AS") {
+			j = skipWords(tokens, j+1, "NOT", "MATERIALIZED")
+			if j < len(tokens) && tokens[j].isPunct("(") {
+				names[strings.ToLower(tokens[i].text)] = true
+			}
+		}
+	}
+	return names
 }
 
 // tokenize splits SQL into words/punctuation
@@ -0,0 +1,128 @@
+package pgterm
+
+import (
+	"database/sql"
+	"time"
+)
+
+// showVariablesSQL lists server settings from pg_settings filtered by a LIKE pattern.
+const showVariablesSQL = `
+    SELECT name, setting, COALESCE(unit, '') AS unit, source, pending_restart
+    FROM pg_settings
+    WHERE name ILIKE $1
+    ORDER BY name;`
+
+// showVariables renders the server settings matching the LIKE pattern.
+func (e *Executor) showVariables(filter showFilter) (string, error) {
+	return e.renderQuery(showVariablesSQL, filter.Like)
+}
+
+// serverVersionNum returns the numeric server version, e.g. 160004 for 16.4.
+func (e *Executor) serverVersionNum() (int, error) {
+	var version int
+	err := e.DB.QueryRow("SELECT current_setting('server_version_num')::int;").Scan(&version)
+	return version, err
+}
+
+// globalStatusSQL returns the single-row queries summarised by SHOW GLOBAL STATUS.
+// Checkpoint counters moved to pg_stat_checkpointer in PostgreSQL 17 and
+// pg_stat_wal exists from PostgreSQL 14.
+func globalStatusSQL(version int) []string {
+	queries := []string{`
+    SELECT sum(numbackends) AS connections,
+           sum(xact_commit) AS commits,
+           sum(xact_rollback) AS rollbacks,
+           sum(blks_read) AS blocks_read,
+           sum(blks_hit) AS blocks_hit,
+           round(100.0 * sum(blks_hit) / NULLIF(sum(blks_hit) + sum(blks_read), 0), 2) AS cache_hit_ratio,
+           sum(tup_returned) AS rows_returned,
+           sum(tup_fetched) AS rows_fetched,
+           sum(tup_inserted) AS rows_inserted,
+           sum(tup_updated) AS rows_updated,
+           sum(tup_deleted) AS rows_deleted,
+           sum(conflicts) AS conflicts,
+           sum(temp_files) AS temp_files,
+           pg_size_pretty(sum(temp_bytes)) AS temp_bytes,
+           sum(deadlocks) AS deadlocks
+    FROM pg_stat_database;`}
+	if version >= 170000 {
+		queries = append(queries, `
+    SELECT num_timed AS checkpoints_timed,
+           num_requested AS checkpoints_requested,
+           buffers_written AS buffers_checkpoint
+    FROM pg_stat_checkpointer;`, `
+    SELECT buffers_clean, maxwritten_clean, buffers_alloc
+    FROM pg_stat_bgwriter;`)
+	} else {
+		queries = append(queries, `
+    SELECT checkpoints_timed,
+           checkpoints_req AS checkpoints_requested,
+           buffers_checkpoint, buffers_clean, maxwritten_clean,
+           buffers_backend, buffers_alloc
+    FROM pg_stat_bgwriter;`)
+	}
+	if version >= 140000 {
+		queries = append(queries, `
+    SELECT wal_records, wal_fpi, pg_size_pretty(wal_bytes) AS wal_bytes, wal_buffers_full
+    FROM pg_stat_wal;`)
+	}
+	return queries
+}
+
+// showGlobalStatus renders server-wide counters as variable/value rows in the
+// style of MySQL's SHOW GLOBAL STATUS.
+func (e *Executor) showGlobalStatus(filter showFilter) (string, error) {
+	now := time.Now()
+	version, err := e.serverVersionNum()
+	if err != nil {
+		return "", err
+	}
+	table := newTable(e.out())
+	table.Header([]string{"variable_name", "value"})
+	rowCount := 0
+	for _, query := range globalStatusSQL(version) {
+		rows, err := e.DB.Query(query)
+		if err != nil {
+			return "", err
+		}
+		names, values, err := scanSingleRow(rows)
+		if err != nil {
+			return "", err
+		}
+		for i, name := range names {
+			if !matchLike(name, filter.Like) {
+				continue
+			}
+			table.Append([]string{name, values[i]})
+			rowCount++
+		}
+	}
+	table.Render()
+	return e.rowsReturned(rowCount, time.Since(now).Seconds()), nil
+}
+
+// scanSingleRow reads the first row of the result set and returns its column
+// names with their formatted values. The rows are closed afterwards.
+func scanSingleRow(rows *sql.Rows) ([]string, []string, error) {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rows.Next() {
+		return columns, make([]string, len(columns)), rows.Err()
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, nil, err
+	}
+	formatted := make([]string, len(columns))
+	for i, val := range values {
+		formatted[i] = formatValue(val)
+	}
+	return columns, formatted, nil
+}
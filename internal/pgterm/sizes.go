@@ -0,0 +1,87 @@
+package pgterm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// showDatabasesSQL lists the databases with owner, encoding, collation, size and
+// connection limit. The size of databases the user cannot connect to is not shown.
+const showDatabasesSQL = `
+    SELECT d.datname,
+           pg_get_userbyid(d.datdba) AS owner,
+           pg_encoding_to_char(d.encoding) AS encoding,
+           d.datcollate AS collation,
+           CASE WHEN has_database_privilege(d.datname, 'CONNECT')
+                THEN pg_size_pretty(pg_database_size(d.datname)) ELSE '' END AS size,
+           CASE WHEN d.datconnlimit < 0 THEN 'unlimited' ELSE d.datconnlimit::text END AS conn_limit
+    FROM pg_database d
+    WHERE d.datistemplate = false
+    ORDER BY d.datname;`
+
+// databaseSizesSQL ranks the databases by size.
+const databaseSizesSQL = `
+    SELECT datname, pg_size_pretty(size) AS size
+    FROM (
+        SELECT datname,
+               CASE WHEN has_database_privilege(datname, 'CONNECT') THEN pg_database_size(datname) END AS size
+        FROM pg_database
+        WHERE datistemplate = false
+    ) d
+    ORDER BY d.size DESC NULLS LAST, datname;`
+
+// schemaSizesSQL ranks the schemas of the current database by the total size of
+// their tables and materialized views, with TOAST and indexes broken out.
+const schemaSizesSQL = `
+    SELECT n.nspname AS schema,
+           count(*) AS tables,
+           pg_size_pretty(sum(pg_relation_size(c.oid))) AS table_size,
+           pg_size_pretty(sum(COALESCE(pg_total_relation_size(NULLIF(c.reltoastrelid, 0)), 0))) AS toast_size,
+           pg_size_pretty(sum(pg_indexes_size(c.oid))) AS index_size,
+           pg_size_pretty(sum(pg_total_relation_size(c.oid))) AS total_size
+    FROM pg_class c
+    JOIN pg_namespace n ON n.oid = c.relnamespace
+    WHERE c.relkind IN ('r', 'm')
+      AND n.nspname !~ '^pg_' AND n.nspname <> 'information_schema'
+    GROUP BY n.nspname
+    ORDER BY sum(pg_total_relation_size(c.oid)) DESC, n.nspname;`
+
+// tableSizesSQL ranks the tables of the current database by total relation size.
+// $1 is the row limit, NULL for all tables.
+const tableSizesSQL = `
+    SELECT n.nspname AS schema,
+           c.relname AS table_name,
+           pg_size_pretty(pg_relation_size(c.oid)) AS table_size,
+           pg_size_pretty(COALESCE(pg_total_relation_size(NULLIF(c.reltoastrelid, 0)), 0)) AS toast_size,
+           pg_size_pretty(pg_indexes_size(c.oid)) AS index_size,
+           pg_size_pretty(pg_total_relation_size(c.oid)) AS total_size
+    FROM pg_class c
+    JOIN pg_namespace n ON n.oid = c.relnamespace
+    WHERE c.relkind IN ('r', 'm')
+      AND n.nspname !~ '^pg_' AND n.nspname <> 'information_schema'
+    ORDER BY pg_total_relation_size(c.oid) DESC, n.nspname, c.relname
+    LIMIT $1;`
+
+// showSizes handles SHOW DATABASE SIZES, SHOW SCHEMA SIZES and
+// SHOW TABLE SIZES [LIMIT n]. args starts after SIZES.
+func (e *Executor) showSizes(kind string, args []string) (string, error) {
+	switch kind {
+	case "DATABASE":
+		return e.renderQuery(databaseSizesSQL)
+	case "SCHEMA":
+		return e.renderQuery(schemaSizesSQL)
+	}
+	var limit interface{}
+	if len(args) > 0 {
+		if len(args) != 2 || strings.ToUpper(args[0]) != "LIMIT" {
+			return "", fmt.Errorf("usage: SHOW TABLE SIZES [LIMIT n]")
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("invalid LIMIT: %s", args[1])
+		}
+		limit = n
+	}
+	return e.renderQuery(tableSizesSQL, limit)
+}
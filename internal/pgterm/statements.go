@@ -0,0 +1,147 @@
+package pgterm
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// slowQueryOrders maps the ORDER BY keywords of SHOW SLOW QUERIES to the
+// expression the report is sorted by. %s is replaced by the time column prefix.
+var slowQueryOrders = map[string]string{
+	"total": "s.total_%stime",
+	"mean":  "s.mean_%stime",
+	"calls": "s.calls",
+	"io":    "s.shared_blks_read + s.shared_blks_written + s.temp_blks_read + s.temp_blks_written",
+}
+
+// slowQueriesSQL reports statements of the current database from pg_stat_statements.
+// The placeholders are the qualified view name, the time column prefix ("exec_"
+// from PostgreSQL 13, empty before) and the ORDER BY expression.
+const slowQueriesSQL = `
+    SELECT pg_get_userbyid(s.userid) AS user,
+           s.calls,
+           round(s.total_%[2]stime::numeric, 2) AS total_ms,
+           round(s.mean_%[2]stime::numeric, 2) AS mean_ms,
+           round(s.stddev_%[2]stime::numeric, 2) AS stddev_ms,
+           s.rows,
+           round(100.0 * s.shared_blks_hit / NULLIF(s.shared_blks_hit + s.shared_blks_read, 0), 2) AS hit_ratio,
+           CASE WHEN $2 > 0 AND length(s.query) > $2
+                THEN left(regexp_replace(s.query, '\s+', ' ', 'g'), $2) || '...'
+                ELSE regexp_replace(s.query, '\s+', ' ', 'g')
+           END AS query
+    FROM %[1]s s
+    WHERE s.dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+    ORDER BY %[3]s DESC
+    LIMIT $1;`
+
+// statementsView returns the qualified name of the pg_stat_statements view and
+// the prefix of its time columns, or an error when the extension is not installed.
+func (e *Executor) statementsView() (string, string, error) {
+	schema, installed, err := e.extensionSchema("pg_stat_statements")
+	if err != nil {
+		return "", "", err
+	}
+	if !installed {
+		return "", "", fmt.Errorf("pg_stat_statements is not installed, run CREATE EXTENSION pg_stat_statements; " +
+			"and add it to shared_preload_libraries")
+	}
+	view := pq.QuoteIdentifier(schema) + ".pg_stat_statements"
+	var execColumns bool
+	if err := e.DB.QueryRow(`
+    SELECT EXISTS (SELECT 1 FROM pg_attribute
+                   WHERE attrelid = $1::regclass AND attname = 'total_exec_time');`, view).Scan(&execColumns); err != nil {
+		return "", "", err
+	}
+	if execColumns {
+		return view, "exec_", nil
+	}
+	return view, "", nil
+}
+
+// SlowQueries renders the statements with the highest total time, mean time,
+// calls or IO. queryLength truncates the query text, 0 shows it in full.
+func (e *Executor) SlowQueries(limit int, orderBy string, queryLength int) (string, error) {
+	order, ok := slowQueryOrders[strings.ToLower(orderBy)]
+	if !ok {
+		return "", fmt.Errorf("cannot order by %s, use total, mean, calls or io", orderBy)
+	}
+	view, prefix, err := e.statementsView()
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(order, "%s") {
+		order = fmt.Sprintf(order, prefix)
+	}
+	return e.renderQuery(fmt.Sprintf(slowQueriesSQL, view, prefix, order), limit, queryLength)
+}
+
+// extensionSchema returns the schema an extension is installed in and whether it
+// is installed at all.
+func (e *Executor) extensionSchema(name string) (string, bool, error) {
+	var schema string
+	err := e.DB.QueryRow(`
+    SELECT n.nspname FROM pg_extension x
+    JOIN pg_namespace n ON n.oid = x.extnamespace
+    WHERE x.extname = $1;`, name).Scan(&schema)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return schema, true, nil
+}
+
+// resetSlowQueries clears pg_stat_statements after confirmation.
+func (e *Executor) resetSlowQueries() (string, error) {
+	if session.GetReadOnly() {
+		return "", fmt.Errorf("Read-only session: RESET SLOW QUERIES is not allowed")
+	}
+	view, _, err := e.statementsView()
+	if err != nil {
+		return "", err
+	}
+	if !confirm("WARNING: This discards all statistics gathered by pg_stat_statements.") {
+		return "", fmt.Errorf("Safe choice. Reset cancelled")
+	}
+	// the reset function lives in the same schema as the view
+	function := strings.TrimSuffix(view, "pg_stat_statements") + "pg_stat_statements_reset()"
+	e.finalSQL = "SELECT " + function + ";"
+	if _, err := e.DB.Exec(e.finalSQL); err != nil {
+		return "", err
+	}
+	return "pg_stat_statements reset", nil
+}
+
+// parseSlowQueries handles SHOW SLOW QUERIES [LIMIT n] [ORDER BY total|mean|calls|io].
+// args starts after QUERIES.
+func (e *Executor) parseSlowQueries(args []string) (string, error) {
+	limit, orderBy := 20, "total"
+	for i := 0; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "LIMIT":
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("LIMIT needs a number")
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				return "", fmt.Errorf("invalid LIMIT: %s", args[i+1])
+			}
+			limit = n
+			i++
+		case "ORDER":
+			if i+2 >= len(args) || strings.ToUpper(args[i+1]) != "BY" {
+				return "", fmt.Errorf("usage: ORDER BY total|mean|calls|io")
+			}
+			orderBy = args[i+2]
+			i += 2
+		default:
+			return "", fmt.Errorf("unexpected %s", args[i])
+		}
+	}
+	return e.SlowQueries(limit, orderBy, processListQueryLength)
+}
@@ -0,0 +1,35 @@
+package pgterm
+
+// tableStatusSQL reports size, row estimate, vacuum and scan statistics for the
+// tables of a schema, largest first.
+const tableStatusSQL = `
+    SELECT c.relname AS name,
+           CASE c.relkind WHEN 'p' THEN 'partitioned' WHEN 'm' THEN 'matview' ELSE 'table' END AS type,
+           GREATEST(c.reltuples, 0)::bigint AS rows_estimate,
+           pg_size_pretty(pg_relation_size(c.oid)) AS table_size,
+           pg_size_pretty(pg_indexes_size(c.oid)) AS index_size,
+           pg_size_pretty(COALESCE(pg_total_relation_size(NULLIF(c.reltoastrelid, 0)), 0)) AS toast_size,
+           pg_size_pretty(pg_total_relation_size(c.oid)) AS total_size,
+           s.n_dead_tup AS dead_tuples,
+           to_char(s.last_vacuum, 'YYYY-MM-DD HH24:MI:SS') AS last_vacuum,
+           to_char(s.last_autovacuum, 'YYYY-MM-DD HH24:MI:SS') AS last_autovacuum,
+           to_char(s.last_analyze, 'YYYY-MM-DD HH24:MI:SS') AS last_analyze,
+           to_char(s.last_autoanalyze, 'YYYY-MM-DD HH24:MI:SS') AS last_autoanalyze,
+           s.seq_scan,
+           s.idx_scan
+    FROM pg_class c
+    JOIN pg_namespace n ON n.oid = c.relnamespace
+    LEFT JOIN pg_stat_user_tables s ON s.relid = c.oid
+    WHERE n.nspname = $1
+      AND c.relkind IN ('r', 'p', 'm')
+      AND c.relname ILIKE $2
+    ORDER BY pg_total_relation_size(c.oid) DESC, c.relname;`
+
+// showTableStatus renders SHOW TABLE STATUS for the schema named by IN, or the active schema.
+func (e *Executor) showTableStatus(filter showFilter) (string, error) {
+	schema := filter.Schema
+	if schema == "" {
+		schema = session.GetSchema()
+	}
+	return e.renderQuery(tableStatusSQL, schema, filter.Like)
+}
@@ -0,0 +1,415 @@
+package pgterm
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c-bata/go-prompt"
+)
+
+// topActivitySQL lists the other client backends for the top view. The duration
+// is the runtime of the current query, or how long an idle session has been idle.
+const topActivitySQL = `
+    SELECT pid,
+           COALESCE(usename, ''),
+           COALESCE(datname, ''),
+           COALESCE(state, ''),
+           COALESCE(wait_event_type || ':' || wait_event, ''),
+           COALESCE(EXTRACT(epoch FROM now() - CASE WHEN state = 'idle' THEN state_change ELSE query_start END), 0),
+           COALESCE(EXTRACT(epoch FROM now() - xact_start), 0),
+           regexp_replace(COALESCE(query, ''), '\s+', ' ', 'g')
+    FROM pg_stat_activity
+    WHERE backend_type = 'client backend' AND pid <> pg_backend_pid();`
+
+// topDatabaseSQL sums the pg_stat_database counters used for the header lines.
+const topDatabaseSQL = `
+    SELECT COALESCE(sum(xact_commit), 0)::bigint,
+           COALESCE(sum(xact_rollback), 0)::bigint,
+           COALESCE(sum(blks_hit), 0)::bigint,
+           COALESCE(sum(blks_read), 0)::bigint,
+           current_setting('max_connections')::int,
+           current_database()
+    FROM pg_stat_database;`
+
+// topSortOrders are the orderings the s key cycles through.
+var topSortOrders = []string{"duration", "xact age", "pid", "user", "database", "state"}
+
+// topInput reads keys for the full-screen views. It is opened once because the
+// underlying /dev/tty descriptor cannot be closed through go-prompt.
+var topInput prompt.ConsoleParser
+
+// topBackend is a row of the top view.
+type topBackend struct {
+	PID       int
+	User      string
+	Database  string
+	State     string
+	WaitEvent string
+	Duration  float64 // query runtime, or idle time for idle sessions
+	XactAge   float64
+	Query     string
+}
+
+// Top is a full-screen, auto-refreshing view of the activity on the server.
+type Top struct {
+	DB       *sql.DB       // Active database connection
+	Interval time.Duration // Time between refreshes
+	ReadOnly bool          // Refuse cancelling and terminating backends
+
+	backends []topBackend
+	sortBy   int
+	filters  map[string]string // user, database and state filters
+	message  string
+
+	// counters of the previous sample, used to compute rates
+	sampledAt time.Time
+	commits   int64
+	rollbacks int64
+	tps       float64
+	header    []string
+
+	// line editor for filters and pids entered at the bottom of the screen
+	editing string
+	input   []rune
+	pending int // pid waiting for confirmation
+}
+
+// Run shows the dashboard until q, Esc or Ctrl-C is pressed.
+func (t *Top) Run() error {
+	if t.Interval <= 0 {
+		t.Interval = 2 * time.Second
+	}
+	if t.filters == nil {
+		t.filters = map[string]string{}
+	}
+	if topInput == nil {
+		topInput = prompt.NewStandardInputParser()
+	}
+	if err := topInput.Setup(); err != nil {
+		return err
+	}
+	defer topInput.TearDown()
+	fmt.Print(enterAltScreen + hideCursor)
+	defer fmt.Print(showCursor + leaveAltScreen)
+
+	t.refresh()
+	t.draw()
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+	for {
+		if b, err := topInput.Read(); err == nil && len(b) > 0 {
+			if t.handleKey(b) {
+				return nil
+			}
+			t.draw()
+		}
+		select {
+		case <-ticker.C:
+			t.refresh()
+			t.draw()
+		default:
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+// refresh samples pg_stat_database and pg_stat_activity.
+func (t *Top) refresh() {
+	var commits, rollbacks, hits, reads int64
+	var maxConnections int
+	var database string
+	if err := t.DB.QueryRow(topDatabaseSQL).Scan(&commits, &rollbacks, &hits, &reads, &maxConnections, &database); err != nil {
+		t.message = err.Error()
+		return
+	}
+	now := time.Now()
+	if !t.sampledAt.IsZero() {
+		elapsed := now.Sub(t.sampledAt).Seconds()
+		if elapsed > 0 {
+			t.tps = float64(commits+rollbacks-t.commits-t.rollbacks) / elapsed
+		}
+	}
+	t.sampledAt, t.commits, t.rollbacks = now, commits, rollbacks
+
+	rows, err := t.DB.Query(topActivitySQL)
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+	defer rows.Close()
+	t.backends = t.backends[:0]
+	states := map[string]int{}
+	for rows.Next() {
+		var b topBackend
+		if err := rows.Scan(&b.PID, &b.User, &b.Database, &b.State, &b.WaitEvent, &b.Duration, &b.XactAge, &b.Query); err != nil {
+			t.message = err.Error()
+			return
+		}
+		states[b.State]++
+		t.backends = append(t.backends, b)
+	}
+
+	hitRatio := 0.0
+	if hits+reads > 0 {
+		hitRatio = 100 * float64(hits) / float64(hits+reads)
+	}
+	t.header = []string{
+		fmt.Sprintf("pgterm top - %s  refresh %s  %s", database, t.Interval, now.Format("15:04:05")),
+		fmt.Sprintf("TPS: %.1f  commits: %d  rollbacks: %d  cache hit ratio: %.2f%%", t.tps, commits, rollbacks, hitRatio),
+		fmt.Sprintf("Connections: %d/%d  active: %d  idle: %d  idle in transaction: %d",
+			len(t.backends)+1, maxConnections, states["active"], states["idle"],
+			states["idle in transaction"]+states["idle in transaction (aborted)"]),
+	}
+}
+
+// visible returns the backends that pass the filters in the selected order.
+func (t *Top) visible() []topBackend {
+	var list []topBackend
+	for _, b := range t.backends {
+		if !containsFold(b.User, t.filters["user"]) || !containsFold(b.Database, t.filters["database"]) ||
+			!containsFold(b.State, t.filters["state"]) {
+			continue
+		}
+		list = append(list, b)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		switch topSortOrders[t.sortBy] {
+		case "xact age":
+			return a.XactAge > b.XactAge
+		case "pid":
+			return a.PID < b.PID
+		case "user":
+			return a.User < b.User
+		case "database":
+			return a.Database < b.Database
+		case "state":
+			return a.State < b.State
+		default:
+			// idle sessions go last, they are not running anything
+			if idleA, idleB := a.State == "idle", b.State == "idle"; idleA != idleB {
+				return idleB
+			}
+			return a.Duration > b.Duration
+		}
+	})
+	return list
+}
+
+// draw repaints the whole screen.
+func (t *Top) draw() {
+	width, height := 120, 40
+	if size := topInput.GetWinSize(); size.Col > 0 && size.Row > 0 {
+		width, height = int(size.Col), int(size.Row)
+	}
+	var out strings.Builder
+	out.WriteString(clearScreen)
+	line := func(s string) {
+		if runes := []rune(s); len(runes) > width {
+			s = string(runes[:width])
+		}
+		out.WriteString(s + "\r\n")
+	}
+	for _, h := range t.header {
+		line(h)
+	}
+	var filters []string
+	for _, name := range []string{"user", "database", "state"} {
+		if value := t.filters[name]; value != "" {
+			filters = append(filters, name+"="+value)
+		}
+	}
+	line(fmt.Sprintf("Sort: %s  Filters: %s", topSortOrders[t.sortBy], strings.Join(filters, " ")))
+	line("")
+	out.WriteString(reverseVideo)
+	line(padRight(fmt.Sprintf("%-7s %-12s %-12s %-20s %-18s %9s %9s  %s",
+		"PID", "USER", "DATABASE", "STATE", "WAIT", "DURATION", "XACT", "QUERY"), width))
+	out.WriteString(resetText)
+
+	// header, sort line, blank line, column header and the two footer lines
+	room := height - len(t.header) - 5
+	for i, b := range t.visible() {
+		if i >= room {
+			break
+		}
+		line(fmt.Sprintf("%-7d %-12.12s %-12.12s %-20.20s %-18.18s %9s %9s  %s",
+			b.PID, b.User, b.Database, b.State, b.WaitEvent,
+			formatSeconds(b.Duration), formatSeconds(b.XactAge), b.Query))
+	}
+
+	out.WriteString(fmt.Sprintf("\x1b[%d;1H", height-1))
+	if t.message != "" {
+		line(boldText + t.message + resetText)
+	} else {
+		line("")
+	}
+	if t.editing != "" {
+		out.WriteString(fmt.Sprintf("%s: %s", t.editPrompt(), string(t.input)))
+	} else {
+		out.WriteString(reverseVideo + padRight("s sort  u user  d database  t state  c cancel  k terminate  r refresh  q quit", width) + resetText)
+	}
+	fmt.Print(out.String())
+}
+
+// editPrompt is the label shown in front of the line editor.
+func (t *Top) editPrompt() string {
+	switch t.editing {
+	case "cancel":
+		return "Cancel query of pid"
+	case "terminate":
+		return "Terminate pid"
+	case "confirm cancel":
+		return fmt.Sprintf("Cancel query of backend %d? (y/n)", t.pending)
+	case "confirm terminate":
+		return fmt.Sprintf("Terminate backend %d? (y/n)", t.pending)
+	default:
+		return "Filter by " + t.editing
+	}
+}
+
+// handleKey processes a key press and reports whether the view should close.
+func (t *Top) handleKey(b []byte) bool {
+	key := prompt.GetKey(b)
+	if t.editing != "" {
+		return t.handleEditKey(key, b)
+	}
+	if key == prompt.ControlC || key == prompt.Escape {
+		return true
+	}
+	t.message = ""
+	switch string(b) {
+	case "q":
+		return true
+	case "s":
+		t.sortBy = (t.sortBy + 1) % len(topSortOrders)
+	case "u":
+		t.startEditing("user")
+	case "d":
+		t.startEditing("database")
+	case "t":
+		t.startEditing("state")
+	case "c", "k":
+		if t.ReadOnly {
+			t.message = "Read-only session: cancelling and terminating backends is not allowed"
+			break
+		}
+		if string(b) == "c" {
+			t.startEditing("cancel")
+		} else {
+			t.startEditing("terminate")
+		}
+	case "r":
+		t.refresh()
+	}
+	return false
+}
+
+// startEditing opens the line editor for a filter or a pid.
+func (t *Top) startEditing(what string) {
+	t.editing = what
+	t.input = []rune(t.filters[what])
+}
+
+// handleEditKey feeds a key to the line editor. Ctrl-C and Esc abort the edit.
+func (t *Top) handleEditKey(key prompt.Key, b []byte) bool {
+	switch key {
+	case prompt.ControlC, prompt.Escape:
+		t.editing = ""
+		return false
+	case prompt.Backspace, prompt.ControlH:
+		if len(t.input) > 0 {
+			t.input = t.input[:len(t.input)-1]
+		}
+		return false
+	case prompt.Enter, prompt.ControlJ, prompt.ControlM:
+		t.finishEditing()
+		return false
+	}
+	if strings.HasPrefix(t.editing, "confirm ") {
+		if answer := strings.ToLower(string(b)); answer == "y" {
+			t.signal(t.editing == "confirm cancel")
+		} else if answer == "n" {
+			t.editing = ""
+		}
+		return false
+	}
+	if key == prompt.NotDefined {
+		t.input = append(t.input, []rune(string(b))...)
+	}
+	return false
+}
+
+// finishEditing applies the text typed in the line editor.
+func (t *Top) finishEditing() {
+	value := strings.TrimSpace(string(t.input))
+	switch t.editing {
+	case "cancel", "terminate":
+		pid, err := strconv.Atoi(value)
+		if err != nil {
+			t.message = fmt.Sprintf("invalid process id: %s", value)
+			t.editing = ""
+			return
+		}
+		t.pending = pid
+		t.editing = "confirm " + t.editing
+	case "confirm cancel", "confirm terminate":
+		// waiting for y or n
+	default:
+		t.filters[t.editing] = value
+		t.editing = ""
+	}
+}
+
+// signal cancels or terminates the confirmed backend.
+func (t *Top) signal(cancelOnly bool) {
+	t.editing = ""
+	start := time.Now()
+	err := sendBackendSignal(t.DB, t.pending, cancelOnly)
+	action := "terminate"
+	if cancelOnly {
+		action = "cancel"
+	}
+	auditLog.Record(fmt.Sprintf(`\top %s %d`, action, t.pending), backendSignalSQL(t.pending, cancelOnly), time.Since(start), 0, err)
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+	if cancelOnly {
+		t.message = fmt.Sprintf("Query cancelled on process %d", t.pending)
+	} else {
+		t.message = fmt.Sprintf("Process %d terminated", t.pending)
+	}
+	t.refresh()
+}
+
+// containsFold reports whether substr is within s, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// padRight pads s with spaces to width characters.
+func padRight(s string, width int) string {
+	if n := len([]rune(s)); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// formatSeconds renders a duration in seconds compactly, e.g. 4.2s, 3m07s or 2h05m.
+func formatSeconds(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second))
+	switch {
+	case seconds <= 0:
+		return ""
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", seconds)
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
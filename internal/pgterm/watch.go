@@ -0,0 +1,124 @@
+package pgterm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Watch re-runs a statement on an interval, redrawing the result each time,
+// until it is interrupted with Ctrl-C or the Until condition is met.
+type Watch struct {
+	Executor  *Executor
+	Statement string
+	Interval  time.Duration
+	// Until stops the watch once the result is "empty" or has "change"d since
+	// the first run. It is ignored when blank.
+	Until string
+}
+
+// parseWatch parses \watch [seconds] [--until empty|change].
+func parseWatch(args []string) (time.Duration, string, error) {
+	interval, until := 2*time.Second, ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--until":
+			if i+1 >= len(args) {
+				return 0, "", fmt.Errorf("--until needs empty or change")
+			}
+			until = strings.ToLower(args[i+1])
+			i++
+		case strings.HasPrefix(args[i], "--until="):
+			until = strings.ToLower(strings.TrimPrefix(args[i], "--until="))
+		default:
+			seconds, err := strconv.ParseFloat(args[i], 64)
+			if err != nil || seconds <= 0 {
+				return 0, "", fmt.Errorf("invalid interval: %s", args[i])
+			}
+			interval = time.Duration(seconds * float64(time.Second))
+		}
+	}
+	if until != "" && until != "empty" && until != "change" {
+		return 0, "", fmt.Errorf("--until needs empty or change, got %s", until)
+	}
+	return interval, until, nil
+}
+
+// watchable returns an error when the statement may not be re-run by \watch. Only
+// statements that return rows and would be allowed in a read-only session are
+// watched, so a change is never applied again on every interval. The watch runs
+// them on a read-only connection as well, which catches writes hidden in functions.
+func watchable(statement string) error {
+	if !returnsRows(statement) {
+		return fmt.Errorf("\\watch only re-runs statements that return rows")
+	}
+	query := statement
+	if fields := strings.Fields(statement); len(fields) > 1 && strings.EqualFold(fields[1], "VISUAL") {
+		inner, commit, err := parseExplainVisual(statement)
+		if err != nil {
+			return err
+		}
+		if commit {
+			return fmt.Errorf("\\watch does not re-run EXPLAIN VISUAL --commit")
+		}
+		query = inner
+	}
+	for _, top := range classifySQL(query) {
+		for _, s := range top.all() {
+			if checkReadOnly(s) != nil {
+				return fmt.Errorf("\\watch only re-runs read-only statements, not %s", s.Verb)
+			}
+		}
+	}
+	return nil
+}
+
+// Run executes the statement until Ctrl-C is pressed or the Until condition is met.
+func (w *Watch) Run() error {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	var first string
+	for run := 0; ; run++ {
+		var out bytes.Buffer
+		w.Executor.Out = &out
+		resp, _, err := w.Executor.Execute(w.Statement)
+		w.Executor.Out = nil
+
+		fmt.Print(clearScreen)
+		fmt.Printf("%s (every %s)\t%s\n\n", time.Now().Format(time.RFC1123), w.Interval, w.Statement)
+		fmt.Print(out.String())
+		if err != nil {
+			fmt.Println(formatError(err, w.Statement, w.Executor.finalSQL))
+			return nil
+		}
+		fmt.Println(resp)
+
+		if run == 0 {
+			first = out.String()
+		}
+		switch w.Until {
+		case "empty":
+			if w.Executor.rowCount == 0 {
+				fmt.Println("Result is empty, watch stopped")
+				return nil
+			}
+		case "change":
+			if run > 0 && out.String() != first {
+				fmt.Println("Result changed, watch stopped")
+				return nil
+			}
+		}
+
+		select {
+		case <-interrupt:
+			return nil
+		case <-time.After(w.Interval):
+		}
+	}
+}